@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/janislaus/figure10/internal/api"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
+	"github.com/janislaus/figure10/internal/textgen"
+)
+
+// backend creates texts and stores sessions, either in the local database or
+// on a remote server
+type backend interface {
+	// NewText generates a text to type
+	NewText() (models.Text, error)
+	// StartSession starts a session on a text and returns its ID
+	StartSession(textID int64) (int64, error)
+	// Finish stores the keystrokes of a session and returns its score
+	Finish(sessionID int64, keystrokes []models.Keystroke) (models.TypingResult, error)
+}
+
+// textOptions selects how new texts are generated
+type textOptions struct {
+	Prompt  string
+	Offline bool
+}
+
+// localBackend works directly on the SQLite database
+type localBackend struct {
+	svc  *service.Service
+	user models.User
+	opts textOptions
+}
+
+func (b *localBackend) NewText() (models.Text, error) {
+	if b.opts.Offline {
+		return b.svc.GenerateOffline(textgen.DefaultOptions(time.Now().UnixNano()))
+	}
+	return b.svc.GenerateText(context.Background(), b.user.ID, b.opts.Prompt, "")
+}
+
+func (b *localBackend) StartSession(textID int64) (int64, error) {
+	session, _, err := b.svc.StartSession(b.user.ID, textID, models.Test{Mode: models.TestText})
+	return session.ID, err
+}
+
+func (b *localBackend) Finish(sessionID int64, keystrokes []models.Keystroke) (models.TypingResult, error) {
+	if err := b.svc.RecordKeystrokes(b.user.ID, sessionID, keystrokes); err != nil {
+		return models.TypingResult{}, err
+	}
+	return b.svc.SubmitSession(b.user.ID, sessionID)
+}
+
+// remoteBackend talks to a Figure10 server over the JSON API
+type remoteBackend struct {
+	client *api.Client
+	opts   textOptions
+}
+
+func (b *remoteBackend) NewText() (models.Text, error) {
+	if b.opts.Offline {
+		return b.client.GenerateOffline()
+	}
+	return b.client.GenerateText(b.opts.Prompt)
+}
+
+func (b *remoteBackend) StartSession(textID int64) (int64, error) {
+	session, err := b.client.StartSession(textID)
+	return session.ID, err
+}
+
+func (b *remoteBackend) Finish(sessionID int64, keystrokes []models.Keystroke) (models.TypingResult, error) {
+	if err := b.client.RecordKeystrokes(sessionID, keystrokes); err != nil {
+		return models.TypingResult{}, err
+	}
+	return b.client.SubmitSession(sessionID)
+}
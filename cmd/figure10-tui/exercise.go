@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ANSI escape sequences used for drawing
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	reset       = "\x1b[0m"
+	bold        = "\x1b[1m"
+	dim         = "\x1b[2m"
+	inverse     = "\x1b[7m"
+	green       = "\x1b[32m"
+	red         = "\x1b[31m"
+	redBack     = "\x1b[41m"
+)
+
+// exercise tracks a text being typed in the terminal
+type exercise struct {
+	text       models.Text
+	expected   []string
+	typed      []string
+	keystrokes []models.Keystroke
+	start      time.Time
+	errors     int
+}
+
+// newExercise creates an exercise for a text
+func newExercise(text models.Text) *exercise {
+	return &exercise{
+		text:     text,
+		expected: scoring.Graphemes(text.Content),
+	}
+}
+
+// press applies a typed character. It is normalized like the web client
+// does, so the keystroke log scores the same on the server.
+func (e *exercise) press(k string) {
+	// A lone combining mark completes the previous character
+	typed := norm.NFC.String(k)
+	combining := scoring.IsCombining(typed) && len(e.typed) > 0
+	if e.done() && !combining {
+		return
+	}
+
+	e.record(k, false)
+	if combining {
+		typed = norm.NFC.String(e.typed[len(e.typed)-1] + typed)
+		e.typed = e.typed[:len(e.typed)-1]
+	}
+	if typed != e.expected[len(e.typed)] {
+		e.errors++
+	}
+	e.typed = append(e.typed, typed)
+}
+
+// enter applies the Enter key, which only counts where the text has a line
+// break
+func (e *exercise) enter() {
+	if !e.done() && e.expected[len(e.typed)] == "\n" {
+		e.record("\n", false)
+		e.typed = append(e.typed, "\n")
+	}
+}
+
+// backspace removes the last typed character
+func (e *exercise) backspace() {
+	if len(e.typed) == 0 {
+		return
+	}
+	e.record("Backspace", true)
+	e.typed = e.typed[:len(e.typed)-1]
+}
+
+// record appends a keystroke event in the format the browser sends
+func (e *exercise) record(k string, isBackspace bool) {
+	if e.start.IsZero() {
+		e.start = time.Now()
+	}
+	e.keystrokes = append(e.keystrokes, models.Keystroke{
+		Seq:       len(e.keystrokes),
+		Key:       k,
+		Timestamp: time.Since(e.start).Milliseconds(),
+		Position:  len(e.typed),
+		Backspace: isBackspace,
+	})
+}
+
+// done reports whether the whole text has been typed
+func (e *exercise) done() bool {
+	return len(e.typed) >= len(e.expected)
+}
+
+// stats returns the live WPM and accuracy
+func (e *exercise) stats() (wpm, accuracy float64) {
+	if len(e.typed) == 0 {
+		return 0, 0
+	}
+
+	correct := 0
+	for i, g := range e.typed {
+		if g == e.expected[i] {
+			correct++
+		}
+	}
+	accuracy = 100 * float64(correct) / float64(len(e.typed))
+
+	if elapsed := time.Since(e.start).Minutes(); elapsed > 0 {
+		wpm = float64(len(e.typed)) / 5 / elapsed
+	}
+	return wpm, accuracy
+}
+
+// render draws the exercise for a terminal of the given width
+func (e *exercise) render(width int) string {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "%sfigure10%s  %s%s%s\r\n\r\n", bold, reset, dim, e.text.Prompt, reset)
+
+	for _, line := range wrap(e.expected, width) {
+		b.WriteString("  ")
+		for i := line.start; i < line.end; i++ {
+			b.WriteString(e.cell(i))
+		}
+		b.WriteString(reset + "\r\n")
+	}
+
+	wpm, accuracy := e.stats()
+	fmt.Fprintf(&b, "\r\n  %sWPM%s %.0f   %sAccuracy%s %.1f%%   %sErrors%s %d   %sEsc to abort%s",
+		bold, reset, wpm, bold, reset, accuracy, bold, reset, e.errors, dim, reset)
+	return b.String()
+}
+
+// cell draws a single character of the text
+func (e *exercise) cell(i int) string {
+	g := e.expected[i]
+	if g == "\n" {
+		g = "↵"
+	}
+
+	switch {
+	case i == len(e.typed):
+		return reset + inverse + g + reset
+	case i > len(e.typed):
+		return reset + g
+	case e.typed[i] == e.expected[i]:
+		return reset + green + g
+	case g == " ":
+		return reset + redBack + g
+	default:
+		return reset + red + g
+	}
+}
+
+// line is a range of grapheme indexes shown on one terminal row
+type line struct {
+	start, end int
+}
+
+// wrap breaks the text into rows of at most width characters, preferring
+// to break after spaces. Line breaks in the text always start a new row.
+func wrap(graphemes []string, width int) []line {
+	var lines []line
+	start, lastSpace := 0, -1
+	for i, g := range graphemes {
+		switch {
+		case g == "\n":
+			lines = append(lines, line{start, i + 1})
+			start, lastSpace = i+1, -1
+			continue
+		case g == " ":
+			lastSpace = i
+		}
+
+		if i-start+1 > width {
+			end := i
+			if lastSpace >= start {
+				end = lastSpace + 1
+			}
+			lines = append(lines, line{start, end})
+			start, lastSpace = end, -1
+		}
+	}
+	if start < len(graphemes) {
+		lines = append(lines, line{start, len(graphemes)})
+	}
+	return lines
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+)
+
+func TestExercisePress(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []string
+		typed  []string
+		errors int
+	}{
+		{"precomposed", []string{"\u00fc", "b"}, []string{"\u00fc", "b"}, 0},
+		{"combining mark completes the character", []string{"u", "\u0308", "b"}, []string{"\u00fc", "b"}, 1},
+		{"decomposed key", []string{"u\u0308"}, []string{"\u00fc"}, 0},
+		{"combining mark after the last character", []string{"\u00fc", "b", "e", "r", "\u0301"}, []string{"\u00fc", "b", "e", "\u0155"}, 1},
+		{"keys after the last character are ignored", []string{"\u00fc", "b", "e", "r", "s"}, []string{"\u00fc", "b", "e", "r"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newExercise(models.Text{Content: "u\u0308ber"})
+			for _, k := range tt.keys {
+				e.press(k)
+			}
+			if !slices.Equal(e.typed, tt.typed) || e.errors != tt.errors {
+				t.Errorf("typed %q with %d errors, want %q with %d", e.typed, e.errors, tt.typed, tt.errors)
+			}
+
+			// The server scores the recorded keystrokes the same way
+			result := scoring.Score(e.text, e.keystrokes)
+			if len(e.keystrokes) > 0 && result.Errors != tt.errors {
+				t.Errorf("server counted %d errors, want %d", result.Errors, tt.errors)
+			}
+		})
+	}
+}
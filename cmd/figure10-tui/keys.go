@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io"
+	"unicode/utf8"
+
+	"github.com/janislaus/figure10/internal/scoring"
+)
+
+// keyKind distinguishes the keys the client reacts to
+type keyKind int
+
+const (
+	keyText keyKind = iota
+	keyBackspace
+	keyEnter
+	keyEscape
+	keyInterrupt
+)
+
+// key is a single key press read from the terminal. Typed text comes as
+// one character per key, a grapheme cluster like the web client records.
+type key struct {
+	kind keyKind
+	text string
+}
+
+// readKeys decodes key presses from a terminal in raw mode and sends them on
+// the returned channel, which is closed when reading fails
+func readKeys(in io.Reader) <-chan key {
+	keys := make(chan key)
+
+	go func() {
+		defer close(keys)
+
+		buf := make([]byte, 64)
+		var pending []byte
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				return
+			}
+			chunk := append(pending, buf[:n]...)
+			pending = nil
+
+			// A read may hold several keys, e.g. when typing fast or
+			// pasting. Text is sent once a control key or escape sequence
+			// ends it, so characters made of several code points stay
+			// together.
+			var text []byte
+			flush := func() {
+				for _, g := range scoring.Graphemes(string(text)) {
+					keys <- key{kind: keyText, text: g}
+				}
+				text = nil
+			}
+
+			for len(chunk) > 0 {
+				if chunk[0] == 0x1b {
+					size, complete := escapeLength(chunk)
+					if !complete {
+						pending = append(pending, chunk...)
+						break
+					}
+					flush()
+					if size == 1 {
+						keys <- key{kind: keyEscape}
+					}
+					chunk = chunk[size:]
+					continue
+				}
+
+				if !utf8.FullRune(chunk) {
+					pending = append(pending, chunk...)
+					break
+				}
+				r, size := utf8.DecodeRune(chunk)
+				switch {
+				case r == 0x03:
+					flush()
+					keys <- key{kind: keyInterrupt}
+				case r == 0x7f, r == 0x08:
+					flush()
+					keys <- key{kind: keyBackspace}
+				case r == '\r', r == '\n':
+					flush()
+					keys <- key{kind: keyEnter}
+				case r >= 0x20 && r != utf8.RuneError:
+					text = append(text, chunk[:size]...)
+				}
+				chunk = chunk[size:]
+			}
+			flush()
+		}
+	}()
+
+	return keys
+}
+
+// escapeLength returns the length of the escape sequence at the start of
+// chunk and whether it is complete. Arrow keys and the like send CSI
+// ("ESC [") or SS3 ("ESC O") sequences and Alt combinations prefix a key
+// with ESC; all of them are skipped. An ESC ending the chunk or followed by
+// another ESC is the Esc key itself and has a length of 1.
+func escapeLength(chunk []byte) (int, bool) {
+	if len(chunk) == 1 || chunk[1] == 0x1b {
+		return 1, true
+	}
+
+	switch chunk[1] {
+	case '[':
+		// Parameter and intermediate bytes up to a final byte
+		for i := 2; i < len(chunk); i++ {
+			if chunk[i] >= 0x40 && chunk[i] <= 0x7e {
+				return i + 1, true
+			}
+			if chunk[i] < 0x20 || chunk[i] > 0x3f {
+				return i, true
+			}
+		}
+		return len(chunk), false
+	case 'O':
+		if len(chunk) < 3 {
+			return len(chunk), false
+		}
+		return 3, true
+	}
+
+	if !utf8.FullRune(chunk[1:]) {
+		return len(chunk), false
+	}
+	_, size := utf8.DecodeRune(chunk[1:])
+	return 1 + size, true
+}
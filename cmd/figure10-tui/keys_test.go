@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"testing"
+)
+
+// chunkReader returns one chunk per read, like a terminal in raw mode
+type chunkReader struct {
+	chunks []string
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func TestReadKeys(t *testing.T) {
+	text := func(s string) key { return key{kind: keyText, text: s} }
+	escape := key{kind: keyEscape}
+
+	tests := []struct {
+		name   string
+		chunks []string
+		keys   []key
+	}{
+		{"single keys", []string{"a", "b"}, []key{text("a"), text("b")}},
+		{"several keys in one read", []string{"ab c"}, []key{text("a"), text("b"), text(" "), text("c")}},
+		{"control keys", []string{"a\x7f\r\x03"}, []key{text("a"), {kind: keyBackspace}, {kind: keyEnter}, {kind: keyInterrupt}}},
+		{"lone escape", []string{"\x1b"}, []key{escape}},
+		{"escape before another escape", []string{"\x1b\x1b[A"}, []key{escape}},
+		{"arrow key between text", []string{"a\x1b[Db"}, []key{text("a"), text("b")}},
+		{"csi with parameters", []string{"\x1b[1;5Cx"}, []key{text("x")}},
+		{"ss3 key", []string{"\x1bOPx"}, []key{text("x")}},
+		{"alt combination", []string{"\x1bfx"}, []key{text("x")}},
+		{"escape sequence split across reads", []string{"\x1b[", "1;5C", "x"}, []key{text("x")}},
+		{"multi-byte character split across reads", []string{"\xc3", "\xbc"}, []key{text("\u00fc")}},
+		{"combining sequence is normalized", []string{"u\u0308ber"}, []key{text("\u00fc"), text("b"), text("e"), text("r")}},
+		{"lone combining mark", []string{"u", "\u0308"}, []key{text("u"), text("\u0308")}},
+		{"emoji with skin tone", []string{"👋🏽!"}, []key{text("👋🏽"), text("!")}},
+		{"other control bytes are ignored", []string{"a\x01b"}, []key{text("a"), text("b")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []key
+			for k := range readKeys(&chunkReader{chunks: tt.chunks}) {
+				got = append(got, k)
+			}
+			if !slices.Equal(got, tt.keys) {
+				t.Errorf("readKeys(%q) = %v, want %v", tt.chunks, got, tt.keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Command figure10-tui is a terminal client for Figure10. It works on the
+// local database by default, or on a remote server with -server.
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/api"
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/llm"
+	"github.com/janislaus/figure10/internal/service"
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/term"
+)
+
+// errAborted is returned when the user quits in the middle of a text
+var errAborted = errors.New("aborted")
+
+func main() {
+	dbPath := flag.String("db", "./figure10.db", "path of the local database")
+	server := flag.String("server", "", "URL of a Figure10 server to use instead of the local database")
+	username := flag.String("user", "", "account to practice as")
+	prompt := flag.String("prompt", service.DefaultPrompt, "prompt for generated texts")
+	offline := flag.Bool("offline", false, "use the offline generator instead of the LLM")
+	flag.Parse()
+
+	opts := textOptions{Prompt: *prompt, Offline: *offline}
+
+	var b backend
+	var err error
+	if *server != "" {
+		b, err = newRemoteBackend(*server, *username, opts)
+	} else {
+		var database *sql.DB
+		database, err = sql.Open("sqlite3", *dbPath)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		defer database.Close()
+		b, err = newLocalBackend(database, *username, opts)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = run(b)
+	if errors.Is(err, errAborted) {
+		fmt.Println("Aborted.")
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newLocalBackend opens the practice account of a user in the local database
+func newLocalBackend(database *sql.DB, username string, opts textOptions) (backend, error) {
+	if username == "" {
+		return nil, fmt.Errorf("-user is required")
+	}
+
+	if _, err := db.Migrate(database); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
+	}
+
+	user, err := db.GetUserByUsername(database, username)
+	if err != nil {
+		return nil, fmt.Errorf("no account named %q, register one in the web UI first", username)
+	}
+
+	provider, err := llm.NewProvider(llm.ConfigFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure text generation: %v", err)
+	}
+
+	svc := service.New(database, llm.NewTextGenerator(provider))
+	return &localBackend{svc: svc, user: user, opts: opts}, nil
+}
+
+// newRemoteBackend logs in to a server. A token in FIGURE10_TOKEN is used
+// as is, otherwise the password of the user is asked for.
+func newRemoteBackend(server, username string, opts textOptions) (backend, error) {
+	client := api.NewClient(server, os.Getenv("FIGURE10_TOKEN"))
+	if client.Token == "" {
+		if username == "" {
+			return nil, fmt.Errorf("-user or FIGURE10_TOKEN is required with -server")
+		}
+
+		fmt.Printf("Password for %s: ", username)
+		password, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := client.Login(username, string(password)); err != nil {
+			return nil, fmt.Errorf("login failed: %v", err)
+		}
+	}
+
+	return &remoteBackend{client: client, opts: opts}, nil
+}
+
+// run drills texts until the user quits
+func run(b backend) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("standard input is not a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	out := bufio.NewWriter(os.Stdout)
+	keys := readKeys(os.Stdin)
+
+	for {
+		fmt.Fprint(out, clearScreen+"Generating text...")
+		out.Flush()
+
+		text, err := b.NewText()
+		if err != nil {
+			return err
+		}
+		sessionID, err := b.StartSession(text.ID)
+		if err != nil {
+			return err
+		}
+
+		ex := newExercise(text)
+		if err := drill(ex, keys, out); err != nil {
+			return err
+		}
+
+		result, err := b.Finish(sessionID, ex.keystrokes)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "\r\n\r\n  %sDone!%s  %.0f WPM, %.1f%% accuracy, %d errors\r\n",
+			bold, reset, result.WPM, result.Accuracy, result.Errors)
+		fmt.Fprintf(out, "  Raw %.0f WPM, net %.0f WPM, %.1f%% keystroke accuracy, %d uncorrected, %.0f%% consistency\r\n",
+			result.RawWPM, result.NetWPM, result.KeystrokeAccuracy, result.UncorrectedErrors, result.Consistency)
+		if len(result.ErrorWords) > 0 {
+			fmt.Fprintf(out, "  Words to practice: %s\r\n", strings.Join(result.ErrorWords, ", "))
+		}
+		fmt.Fprintf(out, "\r\n  %sEnter%s next text   %sEsc%s quit", bold, reset, bold, reset)
+		out.Flush()
+
+		if !waitForNext(keys) {
+			fmt.Fprint(out, "\r\n")
+			out.Flush()
+			return nil
+		}
+	}
+}
+
+// drill lets the user type an exercise until it is complete
+func drill(ex *exercise, keys <-chan key, out *bufio.Writer) error {
+	// Redraw regularly so the WPM stays live during pauses
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	draw := func() {
+		fmt.Fprint(out, ex.render(terminalWidth()))
+		out.Flush()
+	}
+	draw()
+
+	for !ex.done() {
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return errAborted
+			}
+			switch k.kind {
+			case keyEscape, keyInterrupt:
+				return errAborted
+			case keyBackspace:
+				ex.backspace()
+			case keyEnter:
+				ex.enter()
+			case keyText:
+				ex.press(k.text)
+			}
+		case <-ticker.C:
+		}
+		draw()
+	}
+
+	return nil
+}
+
+// waitForNext waits until the user asks for another text or quits
+func waitForNext(keys <-chan key) bool {
+	for k := range keys {
+		switch {
+		case k.kind == keyEnter:
+			return true
+		case k.kind == keyEscape, k.kind == keyInterrupt, k.kind == keyText && k.text == "q":
+			return false
+		}
+	}
+	return false
+}
+
+// terminalWidth returns the usable width of the text area
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	if width > 100 {
+		width = 100
+	}
+	return width - 4
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/service"
+)
+
+// runImport implements the "import" command, which imports files as
+// collections of a user
+func runImport(database *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	username := flags.String("user", "", "user the collections belong to")
+	name := flags.String("name", "", "collection name, defaults to the title of each file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" || flags.NArg() == 0 {
+		return fmt.Errorf("usage: figure10 import -user NAME [-name NAME] FILE...")
+	}
+
+	if _, err := db.Migrate(database); err != nil {
+		return fmt.Errorf("migrating database: %w", err)
+	}
+
+	user, err := db.GetUserByUsername(database, *username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("unknown user %q", *username)
+	}
+	if err != nil {
+		return err
+	}
+
+	svc := service.New(database, nil)
+	for _, filename := range flags.Args() {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+
+		collection, err := svc.ImportFile(user.ID, *name, filename, data)
+		if err != nil {
+			return fmt.Errorf("importing %s: %w", filename, err)
+		}
+		fmt.Printf("Imported %s as %q with %d passage(s)\n", filename, collection.Name, collection.Passages)
+	}
+	return nil
+}
@@ -8,12 +8,18 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/janislaus/figure10/internal/api"
 	"github.com/janislaus/figure10/internal/db"
 	"github.com/janislaus/figure10/internal/handlers"
 	"github.com/janislaus/figure10/internal/llm"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+	"github.com/janislaus/figure10/internal/service"
+	"github.com/janislaus/figure10/internal/snippets"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -25,35 +31,151 @@ func main() {
 	}
 	defer database.Close()
 
-	// Initialize database schema
-	if err := db.InitDB(database); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	// Handle the migrate command
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(database, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Handle the import command
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(database, os.Args[2:]); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		return
+	}
+
+	// Bring the database schema up to date
+	applied, err := db.Migrate(database)
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	if applied > 0 {
+		fmt.Printf("Applied %d database migration(s)\n", applied)
+	}
+
+	// Sessions typed before there were user accounts go to the first account
+	// registered, or to the user named with "migrate adopt"
+	if orphaned, err := db.CountOrphanedSessions(database); err != nil {
+		log.Fatalf("Failed to count sessions: %v", err)
+	} else if users, err := db.CountUsers(database); err != nil {
+		log.Fatalf("Failed to count users: %v", err)
+	} else if orphaned > 0 && users > 0 {
+		fmt.Printf("%d session(s) from before user accounts have no owner, run \"figure10 migrate adopt USERNAME\" to keep them\n", orphaned)
+	}
+
+	// Rate the difficulty of texts saved before texts were rated
+	if rated, err := db.RateTexts(database, scoring.Difficulty); err != nil {
+		log.Fatalf("Failed to rate texts: %v", err)
+	} else if rated > 0 {
+		fmt.Printf("Rated the difficulty of %d text(s)\n", rated)
 	}
 
-	// Update the generator initialization in main.go
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		fmt.Println("Warning: GEMINI_API_KEY environment variable not set. Using fallback text generation.")
+	// Compute the typing test metrics of sessions completed before they existed
+	scored, err := db.ScoreSessions(database, func(text models.Text, keystrokes []models.Keystroke) models.Metrics {
+		return scoring.Score(text, keystrokes).Metrics
+	})
+	if err != nil {
+		log.Fatalf("Failed to score sessions: %v", err)
+	} else if scored > 0 {
+		fmt.Printf("Computed the metrics of %d session(s)\n", scored)
+	}
+
+	// Library search uses FTS5 when SQLite was built with it
+	fullText, err := db.EnableFullTextSearch(database)
+	if err != nil {
+		log.Fatalf("Failed to set up full-text search: %v", err)
+	}
+	if !fullText {
+		fmt.Println("Full-text search is not available, build with make or -tags sqlite_fts5 to enable it. Using LIKE search.")
+	}
+
+	// Select the text generation provider from the environment
+	provider, err := llm.NewProvider(llm.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to configure text generation: %v", err)
+	}
+	if provider.Name() == "fallback" {
+		fmt.Println("Warning: no LLM provider configured. Using fallback text generation.")
 	} else {
-		fmt.Printf("Using Gemini API with key: %s...\n", apiKey[:5]+"...") // Only show first few chars
+		fmt.Printf("Using %s provider for text generation\n", provider.Name())
 	}
-	generator := llm.NewTextGenerator(apiKey)
+	generator := llm.NewTextGenerator(provider)
 
-	// Create handler with dependencies
-	h := handlers.NewHandler(database, generator)
+	// Create the service layer shared by the web UI and the API
+	svc := service.New(database, generator)
+	svc.FullText = fullText
+
+	// Sessions below FIGURE10_MIN_ACCURACY percent don't count for personal
+	// bests and leaderboards
+	if minAccuracy := os.Getenv("FIGURE10_MIN_ACCURACY"); minAccuracy != "" {
+		value, err := strconv.ParseFloat(minAccuracy, 64)
+		if err != nil || value < 0 || value > 100 {
+			log.Fatalf("Invalid FIGURE10_MIN_ACCURACY %q, expected a percentage", minAccuracy)
+		}
+		svc.MinAccuracy = value
+	}
+
+	// Code mode picks snippets from the directory or git work tree in
+	// FIGURE10_CODE_DIR
+	if codeDir := os.Getenv("FIGURE10_CODE_DIR"); codeDir != "" {
+		svc.Code = snippets.NewSource(codeDir)
+		fmt.Printf("Using code snippets from %s\n", codeDir)
+	}
+	h := handlers.NewHandler(svc)
+
+	// Keep texts for generic prompts ready in the background
+	poolConfig, err := poolConfigFromEnv(provider.Name())
+	if err != nil {
+		log.Fatalf("Failed to configure the text pool: %v", err)
+	}
+	poolCtx, stopPool := context.WithCancel(context.Background())
+	poolDone := make(chan struct{})
+	go func() {
+		defer close(poolDone)
+		if poolConfig.Size > 0 {
+			runPool(poolCtx, svc, poolConfig)
+		}
+	}()
 
 	// Set up static file server
 	fs := http.FileServer(http.Dir("./web/static"))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
 
-	// Set up routes
-	http.HandleFunc("/", h.HandleHome)
-	http.HandleFunc("/generate-text", h.HandleGenerateText)
-	http.HandleFunc("/start-session", h.HandleStartSession)
-	http.HandleFunc("/submit-result", h.HandleSubmitResult)
-	http.HandleFunc("/check-typing", h.HandleCheckTyping)
-	http.HandleFunc("/history", h.HandleHistory)
-	http.HandleFunc("/generate-practice", h.HandleGeneratePractice)
+	// Set up account routes
+	http.HandleFunc("/login", h.HandleLogin)
+	http.HandleFunc("/register", h.HandleRegister)
+	http.HandleFunc("/logout", h.HandleLogout)
+
+	// Set up routes for logged-in users
+	http.HandleFunc("/", h.RequireUser(h.HandleHome))
+	http.HandleFunc("/generate-text", h.RequireUser(h.HandleGenerateText))
+	http.HandleFunc("/generate-stream", h.RequireUser(h.HandleGenerateStream))
+	http.HandleFunc("/start-session", h.RequireUser(h.HandleStartSession))
+	http.HandleFunc("/start-test", h.RequireUser(h.HandleStartTest))
+	http.HandleFunc("/extend-text", h.RequireUser(h.HandleExtendText))
+	http.HandleFunc("/keystrokes", h.RequireUser(h.HandleRecordKeystrokes))
+	http.HandleFunc("/submit-result", h.RequireUser(h.HandleSubmitResult))
+	http.HandleFunc("/check-typing", h.RequireUser(h.HandleCheckTyping))
+	http.HandleFunc("/history", h.RequireUser(h.HandleHistory))
+	http.HandleFunc("GET /sessions/{id}", h.RequireUser(h.HandleSession))
+	http.HandleFunc("GET /sessions/{id}/replay", h.RequireUser(h.HandleReplay))
+	http.HandleFunc("/settings", h.RequireUser(h.HandleSettings))
+	http.HandleFunc("/generate-practice", h.RequireUser(h.HandleGeneratePractice))
+	http.HandleFunc("/generate-adaptive", h.RequireUser(h.HandleGenerateAdaptive))
+	http.HandleFunc("/generate-code", h.RequireUser(h.HandleGenerateCode))
+	http.HandleFunc("/import", h.RequireUser(h.HandleImport))
+	http.HandleFunc("/continue-collection", h.RequireUser(h.HandleContinueCollection))
+	http.HandleFunc("/lessons", h.RequireUser(h.HandleLessons))
+	http.HandleFunc("/generate-lesson", h.RequireUser(h.HandleGenerateLesson))
+	http.HandleFunc("/library", h.RequireUser(h.HandleLibrary))
+	http.HandleFunc("/library/tags", h.RequireUser(h.HandleTagText))
+	http.HandleFunc("/leaderboard", h.RequireUser(h.HandleLeaderboard))
+
+	// Set up the JSON API
+	api.New(svc).Register(http.DefaultServeMux)
 
 	// Create server
 	port := "8081"
@@ -78,6 +200,9 @@ func main() {
 	<-stop
 	fmt.Println("\nShutting down server...")
 
+	// Stop refilling the pool, canceling a generation in progress
+	stopPool()
+
 	// Create a deadline for graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -86,6 +211,7 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	<-poolDone
 
 	fmt.Println("Server gracefully stopped")
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/janislaus/figure10/internal/db"
+)
+
+// migrateUsage describes the migrate commands
+const migrateUsage = "usage: figure10 migrate status|up|adopt USERNAME"
+
+// runMigrate implements the "migrate status", "migrate up" and "migrate
+// adopt" commands. Adopt gives the sessions typed before there were user
+// accounts to a user.
+func runMigrate(database *sql.DB, args []string) error {
+	if len(args) == 0 || (len(args) > 1 && args[0] != "adopt") {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "status":
+		states, err := db.MigrationStatus(database)
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			status := "pending"
+			if s.Applied {
+				status = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, status)
+		}
+		return nil
+	case "up":
+		count, err := db.Migrate(database)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migration(s)\n", count)
+		return nil
+	case "adopt":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		if _, err := db.Migrate(database); err != nil {
+			return fmt.Errorf("migrating database: %w", err)
+		}
+		user, err := db.GetUserByUsername(database, args[1])
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("unknown user %q", args[1])
+		}
+		if err != nil {
+			return err
+		}
+		count, err := db.AdoptOrphanedSessions(database, user.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Gave %d session(s) to %s\n", count, user.Username)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected status, up or adopt)", args[0])
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/janislaus/figure10/internal/service"
+)
+
+// poolConfigFromEnv reads the text pool configuration. FIGURE10_POOL_SIZE is
+// the number of texts kept per category and difficulty, 0 turns the pool
+// off. A bucket is refilled once it holds fewer than FIGURE10_POOL_THRESHOLD
+// texts, and at most FIGURE10_POOL_RATE texts are generated per minute.
+// The fallback provider generates its texts instantly, so with it the pool
+// is off unless FIGURE10_POOL_SIZE turns it on.
+func poolConfigFromEnv(provider string) (service.PoolConfig, error) {
+	cfg := service.DefaultPoolConfig()
+	if provider == "fallback" {
+		cfg.Size = 0
+	}
+
+	readInt := func(name string, value *int) error {
+		if s := os.Getenv(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s %q, expected a number", name, s)
+			}
+			*value = n
+		}
+		return nil
+	}
+
+	rate := int(time.Minute / cfg.Interval)
+	if err := readInt("FIGURE10_POOL_SIZE", &cfg.Size); err != nil {
+		return cfg, err
+	}
+	if err := readInt("FIGURE10_POOL_THRESHOLD", &cfg.Threshold); err != nil {
+		return cfg, err
+	}
+	if err := readInt("FIGURE10_POOL_RATE", &rate); err != nil {
+		return cfg, err
+	}
+
+	if rate == 0 {
+		return cfg, fmt.Errorf("FIGURE10_POOL_RATE must be at least 1")
+	}
+	if cfg.Threshold > cfg.Size {
+		cfg.Threshold = cfg.Size
+	}
+	cfg.Interval = time.Minute / time.Duration(rate)
+	return cfg, nil
+}
+
+// runPool keeps the text pool filled until ctx is canceled. A bucket that
+// dropped below the threshold is filled up to the pool size, emptier
+// buckets first, and there is at least the configured interval between two
+// generations.
+func runPool(ctx context.Context, svc *service.Service, cfg service.PoolConfig) {
+	filling := map[service.PoolBucket]bool{}
+	for {
+		if bucket, ok := nextRefill(svc, cfg, filling); ok {
+			err := svc.RefillPool(ctx, bucket)
+			if err != nil && ctx.Err() == nil {
+				fmt.Printf("Failed to refill the text pool: %v\n", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cfg.Interval):
+		}
+	}
+}
+
+// nextRefill returns the bucket to generate a text for next, if any, and
+// updates the buckets being filled
+func nextRefill(svc *service.Service, cfg service.PoolConfig, filling map[service.PoolBucket]bool) (service.PoolBucket, bool) {
+	counts, err := svc.PoolCounts()
+	if err != nil {
+		fmt.Printf("Failed to count the texts in the pool: %v\n", err)
+		return service.PoolBucket{}, false
+	}
+
+	var next service.PoolBucket
+	found := false
+	for _, bucket := range service.PoolBuckets() {
+		count := counts[bucket]
+		if count < cfg.Threshold {
+			filling[bucket] = true
+		} else if count >= cfg.Size {
+			delete(filling, bucket)
+		}
+		if filling[bucket] && (!found || count < counts[next]) {
+			next, found = bucket, true
+		}
+	}
+	return next, found
+}
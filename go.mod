@@ -7,4 +7,10 @@ toolchain go1.23.6
 require (
 	github.com/a-h/templ v0.3.833
 	github.com/mattn/go-sqlite3 v1.14.24
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/crypto v0.31.0
+	golang.org/x/term v0.27.0
+	golang.org/x/text v0.21.0
 )
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,99 @@
+package analytics
+
+import (
+	"sort"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+)
+
+// ErrorRate holds how often a character was mistyped
+type ErrorRate struct {
+	Char     string  `json:"char"`
+	Attempts int     `json:"attempts"`
+	Errors   int     `json:"errors"`
+	Rate     float64 `json:"rate"` // Errors / Attempts
+}
+
+// CharErrorRates computes the error rate of each expected character over
+// the sessions, highest rate first. Characters with fewer than MinSamples
+// attempts are left out.
+func CharErrorRates(logs []models.SessionKeystrokes) []ErrorRate {
+	attempts, errors := countErrors(logs, func(_ models.SessionKeystrokes, char string) string {
+		return char
+	})
+	return errorRates(attempts, errors)
+}
+
+// KeyErrorRates computes the error rate of each key over the sessions,
+// highest rate first. Keys are named by the character they type without
+// modifiers on the layout each session was typed on, so "A" counts toward
+// "a". Characters the layout can't type and keys with fewer than MinSamples
+// attempts are left out.
+func KeyErrorRates(logs []models.SessionKeystrokes) []ErrorRate {
+	attempts, errors := countErrors(logs, func(log models.SessionKeystrokes, char string) string {
+		plain, _ := layoutOf(log.Layout).Plain(char)
+		return plain
+	})
+	return errorRates(attempts, errors)
+}
+
+// ErrorHeatLevels buckets each error rate into levels from 0 (fewest
+// errors) to levels-1 (most), relative to the user's own range
+func ErrorHeatLevels(rates []ErrorRate, levels int) map[string]int {
+	values := map[string]float64{}
+	for _, r := range rates {
+		values[r.Char] = r.Rate
+	}
+	return heatLevels(values, levels)
+}
+
+// errorRates turns the counts of countErrors into error rates, highest rate
+// first. Keys with fewer than MinSamples attempts are left out.
+func errorRates(attempts, errors map[string]int) []ErrorRate {
+	var result []ErrorRate
+	for char, n := range attempts {
+		if n < MinSamples {
+			continue
+		}
+		result = append(result, ErrorRate{
+			Char:     char,
+			Attempts: n,
+			Errors:   errors[char],
+			Rate:     float64(errors[char]) / float64(n),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Rate != result[j].Rate {
+			return result[i].Rate > result[j].Rate
+		}
+		return result[i].Char < result[j].Char
+	})
+	return result
+}
+
+// countErrors counts the attempts and errors of each expected character over
+// the sessions, grouped by the key group returns. Characters with an empty
+// group are left out.
+func countErrors(logs []models.SessionKeystrokes, group func(log models.SessionKeystrokes, char string) string) (attempts, errors map[string]int) {
+	attempts = map[string]int{}
+	errors = map[string]int{}
+	for _, log := range logs {
+		strokes, _ := scoring.ReplayLog(log)
+		for _, s := range strokes {
+			if s.Backspace || s.Expected == "" {
+				continue
+			}
+			key := group(log, s.Expected)
+			if key == "" {
+				continue
+			}
+			attempts[key]++
+			if !s.Correct {
+				errors[key]++
+			}
+		}
+	}
+	return attempts, errors
+}
@@ -0,0 +1,260 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+)
+
+// MaxInterval is the longest gap between two keystrokes that still counts as
+// typing. Longer gaps are pauses and are left out of the latency statistics.
+const MaxInterval = 3 * time.Second
+
+// MinSamples is the number of samples a key or bigram needs before it is
+// reported, so a single slow press doesn't top the list
+const MinSamples = 3
+
+// Latency holds inter-key latency statistics for a character or bigram
+type Latency struct {
+	Key   string  `json:"key"`
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean_ms"`
+	P90   float64 `json:"p90_ms"`
+}
+
+// PeriodLatency holds the overall inter-key latency of a time period
+type PeriodLatency struct {
+	Start time.Time `json:"start"`
+	Latency
+}
+
+// Report bundles the keystroke analytics shown on the history page
+type Report struct {
+	Keys    []Latency         `json:"keys"`
+	Bigrams []Latency         `json:"bigrams"`
+	Weekly  []PeriodLatency   `json:"weekly"`
+	Symbols []SymbolErrorRate `json:"symbols"`
+	Rollups []Rollup          `json:"rollups"`
+}
+
+// sample is a single inter-key interval
+type sample struct {
+	char     string
+	prev     string
+	bigram   string
+	interval float64
+	at       time.Time
+}
+
+// samples extracts the inter-key intervals of a session. Only pairs of
+// consecutive, correctly typed characters count, so corrections and the
+// keys around them don't distort the timing.
+func samples(log models.SessionKeystrokes) []sample {
+	strokes, _ := scoring.ReplayLog(log)
+
+	var result []sample
+	for i := 1; i < len(strokes); i++ {
+		prev, cur := strokes[i-1], strokes[i]
+		if prev.Backspace || cur.Backspace || !prev.Correct || !cur.Correct {
+			continue
+		}
+		if cur.Index != prev.Index+1 {
+			continue
+		}
+
+		interval := float64(cur.Timestamp - prev.Timestamp)
+		if interval <= 0 || interval > float64(MaxInterval.Milliseconds()) {
+			continue
+		}
+
+		result = append(result, sample{
+			char:     cur.Expected,
+			prev:     prev.Expected,
+			bigram:   prev.Expected + cur.Expected,
+			interval: interval,
+			at:       log.CompletedAt,
+		})
+	}
+	return result
+}
+
+// KeyLatencies computes the latency of each character over the sessions
+func KeyLatencies(logs []models.SessionKeystrokes) []Latency {
+	groups := map[string][]float64{}
+	for _, log := range logs {
+		for _, s := range samples(log) {
+			groups[s.char] = append(groups[s.char], s.interval)
+		}
+	}
+	return summarize(groups)
+}
+
+// BigramLatencies computes the latency of each pair of consecutive
+// characters over the sessions, attributed to the second key of the pair
+func BigramLatencies(logs []models.SessionKeystrokes) []Latency {
+	groups := map[string][]float64{}
+	for _, log := range logs {
+		for _, s := range samples(log) {
+			groups[s.bigram] = append(groups[s.bigram], s.interval)
+		}
+	}
+	return summarize(groups)
+}
+
+// WeeklyLatencies computes the overall latency for each week, oldest first
+func WeeklyLatencies(logs []models.SessionKeystrokes) []PeriodLatency {
+	groups := map[time.Time][]float64{}
+	for _, log := range logs {
+		week := startOfWeek(log.CompletedAt)
+		for _, s := range samples(log) {
+			groups[week] = append(groups[week], s.interval)
+		}
+	}
+
+	var result []PeriodLatency
+	for week, intervals := range groups {
+		result = append(result, PeriodLatency{
+			Start:   week,
+			Latency: summarizeOne("", intervals),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result
+}
+
+// NewReport computes all keystroke analytics of the sessions and rolls them
+// up together with the recorded typing errors, with the latency tables
+// sorted by the given column
+func NewReport(logs []models.SessionKeystrokes, typingErrors []models.LayoutError, sortBy string) Report {
+	report := Report{
+		Keys:    KeyLatencies(logs),
+		Bigrams: BigramLatencies(logs),
+		Weekly:  WeeklyLatencies(logs),
+		Symbols: SymbolErrorRates(logs),
+	}
+	for _, by := range Groupings {
+		report.Rollups = append(report.Rollups, NewRollup(logs, typingErrors, by))
+	}
+	SortLatencies(report.Keys, sortBy)
+	SortLatencies(report.Bigrams, sortBy)
+	for _, rollup := range report.Rollups {
+		SortLatencies(rollup.Bigrams, sortBy)
+	}
+	return report
+}
+
+// SortLatencies sorts by "key", "count", "mean" or "p90". Numeric columns
+// are sorted slowest or most frequent first; unknown columns sort by p90.
+func SortLatencies(latencies []Latency, sortBy string) {
+	less := func(a, b Latency) bool { return a.P90 > b.P90 }
+	switch sortBy {
+	case "key":
+		less = func(a, b Latency) bool { return a.Key < b.Key }
+	case "count":
+		less = func(a, b Latency) bool { return a.Count > b.Count }
+	case "mean":
+		less = func(a, b Latency) bool { return a.Mean > b.Mean }
+	}
+
+	sort.SliceStable(latencies, func(i, j int) bool {
+		if less(latencies[i], latencies[j]) {
+			return true
+		}
+		if less(latencies[j], latencies[i]) {
+			return false
+		}
+		return latencies[i].Key < latencies[j].Key
+	})
+}
+
+// HeatLevels buckets each key's mean latency into levels from 0 (fastest)
+// to levels-1 (slowest), relative to the user's own range
+func HeatLevels(latencies []Latency, levels int) map[string]int {
+	values := map[string]float64{}
+	for _, l := range latencies {
+		values[l.Key] = l.Mean
+	}
+	return heatLevels(values, levels)
+}
+
+// heatLevels buckets values into levels from 0 (lowest) to levels-1
+// (highest), relative to the range of the values
+func heatLevels(values map[string]float64, levels int) map[string]int {
+	result := map[string]int{}
+	if len(values) == 0 || levels < 1 {
+		return result
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	for key, v := range values {
+		level := 0
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(levels))
+			if level >= levels {
+				level = levels - 1
+			}
+		}
+		result[key] = level
+	}
+	return result
+}
+
+// summarize computes statistics for each group with enough samples
+func summarize(groups map[string][]float64) []Latency {
+	var result []Latency
+	for key, intervals := range groups {
+		if len(intervals) < MinSamples {
+			continue
+		}
+		result = append(result, summarizeOne(key, intervals))
+	}
+	SortLatencies(result, "p90")
+	return result
+}
+
+// summarizeOne computes the mean and 90th percentile of the intervals
+func summarizeOne(key string, intervals []float64) Latency {
+	sorted := append([]float64(nil), intervals...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+
+	return Latency{
+		Key:   key,
+		Count: len(sorted),
+		Mean:  sum / float64(len(sorted)),
+		P90:   percentile(sorted, 0.9),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// startOfWeek returns midnight of the Monday starting the week of t
+func startOfWeek(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,174 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// Progress periods
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+// RollingWindow is the number of periods the rolling averages span
+var RollingWindow = map[string]int{
+	PeriodDay:  7,
+	PeriodWeek: 4,
+}
+
+// ProgressBucket aggregates the sessions of a day or week. Means are zero
+// for periods without sessions.
+type ProgressBucket struct {
+	Start           time.Time `json:"start"`
+	Sessions        int       `json:"sessions"`
+	MeanWPM         float64   `json:"mean_wpm"`
+	BestWPM         float64   `json:"best_wpm"`
+	MeanAccuracy    float64   `json:"mean_accuracy"`
+	PracticeSeconds float64   `json:"practice_seconds"`
+	Characters      int       `json:"characters"`
+
+	// Rolling averages over the sessions of this and the preceding periods
+	// of the rolling window
+	RollingWPM      float64 `json:"rolling_wpm"`
+	RollingAccuracy float64 `json:"rolling_accuracy"`
+}
+
+// ProgressSummary totals a date range. WPMChange is the difference between
+// the last and the first rolling WPM of the range.
+type ProgressSummary struct {
+	Sessions        int     `json:"sessions"`
+	MeanWPM         float64 `json:"mean_wpm"`
+	BestWPM         float64 `json:"best_wpm"`
+	MeanAccuracy    float64 `json:"mean_accuracy"`
+	PracticeSeconds float64 `json:"practice_seconds"`
+	Characters      int     `json:"characters"`
+	WPMChange       float64 `json:"wpm_change"`
+}
+
+// ProgressReport holds the progress statistics of a date range
+type ProgressReport struct {
+	Period  string           `json:"period"`
+	From    time.Time        `json:"from"`
+	To      time.Time        `json:"to"`
+	Buckets []ProgressBucket `json:"buckets"`
+	Summary ProgressSummary  `json:"summary"`
+}
+
+// PeriodStart returns the start of the day or week containing t
+func PeriodStart(t time.Time, period string) time.Time {
+	if period == PeriodWeek {
+		return startOfWeek(t)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// ShiftPeriod moves the start of a period by n periods
+func ShiftPeriod(t time.Time, period string, n int) time.Time {
+	if period == PeriodWeek {
+		return t.AddDate(0, 0, 7*n)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+// totals accumulates sessions
+type totals struct {
+	sessions   int
+	wpm        float64
+	accuracy   float64
+	best       float64
+	seconds    float64
+	characters int
+}
+
+func (t *totals) add(s models.SessionSample) {
+	t.sessions++
+	t.wpm += s.WPM
+	t.accuracy += s.Accuracy
+	t.best = max(t.best, s.WPM)
+	t.seconds += s.Duration.Seconds()
+	t.characters += s.Characters
+}
+
+func (t *totals) merge(o totals) {
+	t.sessions += o.sessions
+	t.wpm += o.wpm
+	t.accuracy += o.accuracy
+	t.best = max(t.best, o.best)
+	t.seconds += o.seconds
+	t.characters += o.characters
+}
+
+// means returns the mean WPM and accuracy, or zeros without sessions
+func (t totals) means() (float64, float64) {
+	if t.sessions == 0 {
+		return 0, 0
+	}
+	return t.wpm / float64(t.sessions), t.accuracy / float64(t.sessions)
+}
+
+// Progress aggregates sessions into a bucket for every day or week from the
+// period containing from to the one containing to, oldest first. Sessions
+// before from still count toward the rolling averages of the first buckets.
+func Progress(samples []models.SessionSample, period string, from, to time.Time) ProgressReport {
+	report := ProgressReport{Period: period, From: from, To: to}
+
+	groups := map[time.Time]*totals{}
+	for _, s := range samples {
+		start := PeriodStart(s.CompletedAt, period)
+		if groups[start] == nil {
+			groups[start] = &totals{}
+		}
+		groups[start].add(s)
+	}
+
+	window := RollingWindow[period]
+	var summary totals
+	first, last := -1.0, 0.0
+	for start := PeriodStart(from, period); !start.After(to); start = ShiftPeriod(start, period, 1) {
+		var current totals
+		if g := groups[start]; g != nil {
+			current = *g
+		}
+		summary.merge(current)
+
+		// The rolling window covers this period and the ones before it
+		var rolling totals
+		for i := 0; i < window; i++ {
+			if g := groups[ShiftPeriod(start, period, -i)]; g != nil {
+				rolling.merge(*g)
+			}
+		}
+
+		bucket := ProgressBucket{
+			Start:           start,
+			Sessions:        current.sessions,
+			BestWPM:         current.best,
+			PracticeSeconds: current.seconds,
+			Characters:      current.characters,
+		}
+		bucket.MeanWPM, bucket.MeanAccuracy = current.means()
+		bucket.RollingWPM, bucket.RollingAccuracy = rolling.means()
+		report.Buckets = append(report.Buckets, bucket)
+
+		if rolling.sessions > 0 {
+			if first < 0 {
+				first = bucket.RollingWPM
+			}
+			last = bucket.RollingWPM
+		}
+	}
+
+	report.Summary = ProgressSummary{
+		Sessions:        summary.sessions,
+		BestWPM:         summary.best,
+		PracticeSeconds: summary.seconds,
+		Characters:      summary.characters,
+	}
+	report.Summary.MeanWPM, report.Summary.MeanAccuracy = summary.means()
+	if first >= 0 {
+		report.Summary.WPMChange = last - first
+	}
+	return report
+}
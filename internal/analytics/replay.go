@@ -0,0 +1,67 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+)
+
+// MinPause is the shortest gap between two keystrokes a replay marks as a
+// pause. It is shorter than MaxInterval to show where the typist hesitated.
+const MinPause = time.Second
+
+// Pause is a gap between two keystrokes of a replay
+type Pause struct {
+	Stroke   int   `json:"stroke"`      // index of the stroke ending the pause
+	Start    int64 `json:"start_ms"`    // since the first keystroke
+	Duration int64 `json:"duration_ms"` // until the stroke ending the pause
+	Position int   `json:"position"`    // length of the input during the pause
+}
+
+// Replay is the keystroke log of a session prepared for playback. Times are
+// in milliseconds since the first keystroke.
+type Replay struct {
+	Strokes  []scoring.Stroke `json:"strokes"`
+	Duration int64            `json:"duration_ms"`
+	Speed    []float64        `json:"speed"` // the WPM typed in each second
+	Pauses   []Pause          `json:"pauses"`
+}
+
+// NewReplay replays the keystroke log of a session for playback
+func NewReplay(log models.SessionKeystrokes) Replay {
+	strokes, _ := scoring.ReplayLog(log)
+	replay := Replay{
+		Strokes: []scoring.Stroke{},
+		Speed:   []float64{},
+		Pauses:  []Pause{},
+	}
+	if len(strokes) == 0 {
+		return replay
+	}
+
+	start := strokes[0].Timestamp
+	for i := range strokes {
+		strokes[i].Timestamp -= start
+	}
+	replay.Strokes = strokes
+	replay.Duration = strokes[len(strokes)-1].Timestamp
+
+	// Five characters make a word, so a character per second is 12 WPM
+	for _, count := range scoring.CharsPerSecond(strokes) {
+		replay.Speed = append(replay.Speed, count*12)
+	}
+
+	for i := 1; i < len(strokes); i++ {
+		gap := strokes[i].Timestamp - strokes[i-1].Timestamp
+		if gap >= MinPause.Milliseconds() {
+			replay.Pauses = append(replay.Pauses, Pause{
+				Stroke:   i,
+				Start:    strokes[i-1].Timestamp,
+				Duration: gap,
+				Position: strokes[i-1].Length,
+			})
+		}
+	}
+	return replay
+}
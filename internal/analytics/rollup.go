@@ -0,0 +1,172 @@
+package analytics
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/janislaus/figure10/internal/layouts"
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// Groupings of keys by what types them
+const (
+	ByFinger = "finger"
+	ByHand   = "hand"
+	ByRow    = "row"
+)
+
+// Groupings lists every grouping of keys
+var Groupings = []string{ByFinger, ByHand, ByRow}
+
+// GroupCount counts the recorded typing errors of a finger, hand or row
+type GroupCount struct {
+	Group string `json:"group"`
+	Count int    `json:"count"`
+}
+
+// Rollup holds the error and latency statistics of the keys rolled up by
+// finger, hand or row. The groups take the place of the characters: error
+// rates and key latencies are reported per group in the order of the
+// grouping, and bigram latencies per pair of groups, such as "left→right".
+// Each session is rolled up on the layout it was typed on, so the fingers
+// stay comparable across a switch of layouts.
+type Rollup struct {
+	By       string          `json:"by"`
+	Errors   []ErrorRate     `json:"errors"`
+	Mistakes []GroupCount    `json:"mistakes"`
+	Keys     []Latency       `json:"keys"`
+	Bigrams  []Latency       `json:"bigrams"`
+	Weekly   []PeriodLatency `json:"weekly"`
+}
+
+// Groups returns the groups of a grouping in order, left to right or top to
+// bottom
+func Groups(by string) []string {
+	switch by {
+	case ByFinger:
+		return layouts.Fingers
+	case ByHand:
+		return layouts.Hands
+	case ByRow:
+		return layouts.Rows
+	}
+	return nil
+}
+
+// GroupOf returns the finger, hand or row typing a character on a layout,
+// or "" if the layout has no key for it or the grouping doesn't apply, like
+// the hand of the space bar
+func GroupOf(layout *layouts.Layout, char, by string) string {
+	key, ok := layout.Key(char)
+	if !ok {
+		return ""
+	}
+	switch by {
+	case ByFinger:
+		return key.Finger
+	case ByHand:
+		return key.Hand
+	case ByRow:
+		return key.Row
+	}
+	return ""
+}
+
+// layoutOf returns the layout with an ID, or the default layout for
+// unknown IDs
+func layoutOf(id string) *layouts.Layout {
+	if layout, ok := layouts.Get(id); ok {
+		return layout
+	}
+	layout, _ := layouts.Get(layouts.Default)
+	return layout
+}
+
+// NewRollup rolls the error rates, typing errors and latencies of the
+// sessions up by finger, hand or row
+func NewRollup(logs []models.SessionKeystrokes, typingErrors []models.LayoutError, by string) Rollup {
+	groups := Groups(by)
+	rollup := Rollup{
+		By:       by,
+		Errors:   []ErrorRate{},
+		Mistakes: []GroupCount{},
+		Keys:     []Latency{},
+		Bigrams:  []Latency{},
+		Weekly:   []PeriodLatency{},
+	}
+
+	attempts, errors := countErrors(logs, func(log models.SessionKeystrokes, char string) string {
+		return GroupOf(layoutOf(log.Layout), char, by)
+	})
+	for _, group := range groups {
+		if n := attempts[group]; n >= MinSamples {
+			rollup.Errors = append(rollup.Errors, ErrorRate{
+				Char:     group,
+				Attempts: n,
+				Errors:   errors[group],
+				Rate:     float64(errors[group]) / float64(n),
+			})
+		}
+	}
+
+	mistakes := map[string]int{}
+	for _, e := range typingErrors {
+		if group := GroupOf(layoutOf(e.Layout), e.ExpectedChar, by); group != "" {
+			mistakes[group] += e.Count
+		}
+	}
+	for _, group := range groups {
+		if mistakes[group] > 0 {
+			rollup.Mistakes = append(rollup.Mistakes, GroupCount{Group: group, Count: mistakes[group]})
+		}
+	}
+
+	keys := map[string][]float64{}
+	bigrams := map[string][]float64{}
+	weekly := map[time.Time]map[string][]float64{}
+	for _, log := range logs {
+		layout := layoutOf(log.Layout)
+		week := startOfWeek(log.CompletedAt)
+		for _, s := range samples(log) {
+			group := GroupOf(layout, s.char, by)
+			if group == "" {
+				continue
+			}
+			keys[group] = append(keys[group], s.interval)
+			if weekly[week] == nil {
+				weekly[week] = map[string][]float64{}
+			}
+			weekly[week][group] = append(weekly[week][group], s.interval)
+
+			if prev := GroupOf(layout, s.prev, by); prev != "" {
+				pair := prev + "→" + group
+				bigrams[pair] = append(bigrams[pair], s.interval)
+			}
+		}
+	}
+
+	for _, group := range groups {
+		if len(keys[group]) >= MinSamples {
+			rollup.Keys = append(rollup.Keys, summarizeOne(group, keys[group]))
+		}
+	}
+	if pairs := summarize(bigrams); pairs != nil {
+		rollup.Bigrams = pairs
+	}
+	for week, intervals := range weekly {
+		for group, values := range intervals {
+			if len(values) >= MinSamples {
+				rollup.Weekly = append(rollup.Weekly, PeriodLatency{Start: week, Latency: summarizeOne(group, values)})
+			}
+		}
+	}
+	sort.Slice(rollup.Weekly, func(i, j int) bool {
+		a, b := rollup.Weekly[i], rollup.Weekly[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		return slices.Index(groups, a.Key) < slices.Index(groups, b.Key)
+	})
+	return rollup
+}
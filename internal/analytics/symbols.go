@@ -0,0 +1,87 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// Symbol classes
+const (
+	SymbolBracket     = "bracket"
+	SymbolOperator    = "operator"
+	SymbolQuote       = "quote"
+	SymbolPunctuation = "punctuation"
+)
+
+// SymbolErrorRate is the error rate of a programming symbol
+type SymbolErrorRate struct {
+	ErrorRate
+	Class string `json:"class"`
+}
+
+// SymbolClass returns the class of a programming symbol, or "" for letters,
+// digits, whitespace and anything else
+func SymbolClass(char string) string {
+	if len(char) != 1 {
+		return ""
+	}
+	switch {
+	case strings.Contains("()[]{}", char):
+		return SymbolBracket
+	case strings.Contains("+-*/%=<>!&|^~", char):
+		return SymbolOperator
+	case strings.Contains("\"'`", char):
+		return SymbolQuote
+	case strings.Contains(".,;:?@#$_\\", char):
+		return SymbolPunctuation
+	}
+	return ""
+}
+
+// SymbolErrorRates computes the error rate of each symbol typed in code
+// sessions, highest rate first
+func SymbolErrorRates(logs []models.SessionKeystrokes) []SymbolErrorRate {
+	var code []models.SessionKeystrokes
+	for _, log := range logs {
+		if log.Kind == models.TextKindCode {
+			code = append(code, log)
+		}
+	}
+
+	var result []SymbolErrorRate
+	for _, rate := range CharErrorRates(code) {
+		if class := SymbolClass(rate.Char); class != "" {
+			result = append(result, SymbolErrorRate{ErrorRate: rate, Class: class})
+		}
+	}
+	return result
+}
+
+// SymbolClassRates sums the symbol error rates per class, highest rate first
+func SymbolClassRates(symbols []SymbolErrorRate) []ErrorRate {
+	totals := map[string]*ErrorRate{}
+	for _, s := range symbols {
+		total, ok := totals[s.Class]
+		if !ok {
+			total = &ErrorRate{Char: s.Class}
+			totals[s.Class] = total
+		}
+		total.Attempts += s.Attempts
+		total.Errors += s.Errors
+	}
+
+	var result []ErrorRate
+	for _, total := range totals {
+		total.Rate = float64(total.Errors) / float64(total.Attempts)
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Rate != result[j].Rate {
+			return result[i].Rate > result[j].Rate
+		}
+		return result[i].Char < result[j].Char
+	})
+	return result
+}
@@ -0,0 +1,87 @@
+package analytics
+
+import (
+	"strings"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+)
+
+// WordStat holds how a word of the text was typed in a session
+type WordStat struct {
+	Word       string  `json:"word"`
+	Position   int     `json:"position"` // of the first character in the text
+	Typed      bool    `json:"typed"`    // whether the typist got to the word
+	Correct    bool    `json:"correct"`  // whether the word was right in the end
+	Mistakes   int     `json:"mistakes"` // wrong key presses, corrected or not
+	Duration   int64   `json:"duration_ms"`
+	WPM        float64 `json:"wpm"`           // zero for words typed too fast to time
+	Hesitation int64   `json:"hesitation_ms"` // gap before the first key of the word
+}
+
+// Words breaks a session down into the words of its text, the runs of
+// characters between whitespace. A word is timed from the key before it,
+// usually the space, to the last key that typed or deleted one of its
+// characters, so corrections count toward the word. The first word of the
+// text is timed from its first key.
+func Words(log models.SessionKeystrokes) []WordStat {
+	expected := scoring.Graphemes(log.Content)
+	strokes, input := scoring.ReplayLog(log)
+
+	// The words and the word of each character of the text
+	words := []WordStat{}
+	wordAt := make([]int, len(expected))
+	for i, char := range expected {
+		wordAt[i] = -1
+		if strings.TrimSpace(char) == "" {
+			continue
+		}
+		if i == 0 || wordAt[i-1] < 0 {
+			words = append(words, WordStat{Position: i, Correct: true})
+		}
+		w := len(words) - 1
+		words[w].Word += char
+		wordAt[i] = w
+		if i >= len(input) || input[i] != char {
+			words[w].Correct = false
+		}
+	}
+
+	// The first and last stroke applied to each word
+	first := make([]int, len(words))
+	last := make([]int, len(words))
+	for i, s := range strokes {
+		if s.Index >= len(expected) || wordAt[s.Index] < 0 {
+			continue
+		}
+		w := wordAt[s.Index]
+		if !words[w].Typed {
+			words[w].Typed = true
+			first[w] = i
+		}
+		last[w] = i
+		if !s.Backspace && !s.Correct {
+			words[w].Mistakes++
+		}
+	}
+
+	for w := range words {
+		word := &words[w]
+		if !word.Typed {
+			continue
+		}
+		start := strokes[first[w]].Timestamp
+		chars := len(scoring.Graphemes(word.Word))
+		if first[w] > 0 {
+			word.Hesitation = start - strokes[first[w]-1].Timestamp
+			start = strokes[first[w]-1].Timestamp
+		} else {
+			chars--
+		}
+		word.Duration = strokes[last[w]].Timestamp - start
+		if word.Duration > 0 && chars > 0 {
+			word.WPM = float64(chars) / 5 / (float64(word.Duration) / 60000)
+		}
+	}
+	return words
+}
@@ -0,0 +1,196 @@
+// Package api serves Figure10's versioned JSON API under /api/v1. Every
+// response is JSON; failures have the shape
+//
+//	{"error": {"code": "not_found", "message": "Session not found"}}
+//
+// Clients authenticate with a token from POST /api/v1/login, sent as
+// "Authorization: Bearer <token>". The browser's login cookie works too.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/janislaus/figure10/internal/auth"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
+)
+
+// Prefix is the path prefix of the current API version
+const Prefix = "/api/v1"
+
+// API holds dependencies for the API handlers
+type API struct {
+	Service *service.Service
+}
+
+// New creates a new API with the given dependencies
+func New(svc *service.Service) *API {
+	return &API{
+		Service: svc,
+	}
+}
+
+// Register adds the API routes to mux
+func (a *API) Register(mux *http.ServeMux) {
+	// Account routes
+	mux.HandleFunc("POST "+Prefix+"/register", a.handleRegister)
+	mux.HandleFunc("POST "+Prefix+"/login", a.handleLogin)
+	mux.HandleFunc("POST "+Prefix+"/logout", a.requireUser(a.handleLogout))
+	mux.HandleFunc("GET "+Prefix+"/me", a.requireUser(a.handleMe))
+	mux.HandleFunc("GET "+Prefix+"/settings", a.requireUser(a.handleGetSettings))
+	mux.HandleFunc("PUT "+Prefix+"/settings", a.requireUser(a.handlePutSettings))
+
+	// Texts
+	mux.HandleFunc("POST "+Prefix+"/texts", a.requireUser(a.handleCreateText))
+	mux.HandleFunc("GET "+Prefix+"/texts", a.requireUser(a.handleListTexts))
+	mux.HandleFunc("GET "+Prefix+"/texts/{id}", a.requireUser(a.handleGetText))
+	mux.HandleFunc("POST "+Prefix+"/texts/{id}/check", a.requireUser(a.handleCheckText))
+	mux.HandleFunc("PUT "+Prefix+"/texts/{id}/tags", a.requireUser(a.handleSetTags))
+
+	// Library
+	mux.HandleFunc("GET "+Prefix+"/library", a.requireUser(a.handleLibrary))
+	mux.HandleFunc("GET "+Prefix+"/tags", a.requireUser(a.handleListTags))
+
+	// Imported collections
+	mux.HandleFunc("POST "+Prefix+"/collections", a.requireUser(a.handleImport))
+	mux.HandleFunc("GET "+Prefix+"/collections", a.requireUser(a.handleListCollections))
+	mux.HandleFunc("GET "+Prefix+"/collections/{id}", a.requireUser(a.handleGetCollection))
+	mux.HandleFunc("POST "+Prefix+"/collections/{id}/next", a.requireUser(a.handleNextPassage))
+
+	// Lessons
+	mux.HandleFunc("GET "+Prefix+"/lessons", a.requireUser(a.handleLessons))
+
+	// Sessions
+	mux.HandleFunc("POST "+Prefix+"/sessions", a.requireUser(a.handleStartSession))
+	mux.HandleFunc("GET "+Prefix+"/sessions", a.requireUser(a.handleListSessions))
+	mux.HandleFunc("GET "+Prefix+"/sessions/{id}", a.requireUser(a.handleGetSession))
+	mux.HandleFunc("GET "+Prefix+"/sessions/{id}/replay", a.requireUser(a.handleReplay))
+	mux.HandleFunc("POST "+Prefix+"/sessions/{id}/keystrokes", a.requireUser(a.handleRecordKeystrokes))
+	mux.HandleFunc("POST "+Prefix+"/sessions/{id}/submit", a.requireUser(a.handleSubmitSession))
+	mux.HandleFunc("POST "+Prefix+"/sessions/{id}/extend", a.requireUser(a.handleExtendText))
+	mux.HandleFunc("POST "+Prefix+"/tests", a.requireUser(a.handleStartTest))
+
+	// Analytics
+	mux.HandleFunc("GET "+Prefix+"/errors", a.requireUser(a.handleErrors))
+	mux.HandleFunc("GET "+Prefix+"/errors/keys", a.requireUser(a.handleKeyErrors))
+	mux.HandleFunc("GET "+Prefix+"/stats", a.requireUser(a.handleStats))
+	mux.HandleFunc("GET "+Prefix+"/stats/progress", a.requireUser(a.handleProgress))
+	mux.HandleFunc("GET "+Prefix+"/bests", a.requireUser(a.handleBests))
+
+	// Leaderboards
+	mux.HandleFunc("GET "+Prefix+"/texts/{id}/leaderboard", a.requireUser(a.handleTextLeaderboard))
+	mux.HandleFunc("GET "+Prefix+"/challenge", a.requireUser(a.handleChallenge))
+
+	// Anything else under the prefix
+	mux.HandleFunc(Prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, &service.Error{Code: service.CodeNotFound, Message: "Unknown API endpoint"})
+	})
+}
+
+// userHandler is an API handler for a logged-in user
+type userHandler func(w http.ResponseWriter, r *http.Request, user models.User)
+
+// requireUser wraps a handler so it only runs for authenticated requests
+func (a *API) requireUser(next userHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := requestToken(r)
+		if token == "" {
+			writeError(w, &service.Error{Code: service.CodeUnauthorized, Message: "Missing bearer token"})
+			return
+		}
+
+		user, err := a.Service.UserByToken(token)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, token)), user)
+	}
+}
+
+// tokenKey is the request context key of the token a request was made with
+type tokenKey struct{}
+
+// requestToken returns the bearer token of a request, falling back to the
+// browser's login cookie
+func requestToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, _ := strings.Cut(header, " ")
+		if strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if cookie, err := r.Cookie(auth.CookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// errorBody is the JSON body of a failed request
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a structured error response. Internal errors are
+// logged and reported without details.
+func writeError(w http.ResponseWriter, err error) {
+	var body errorBody
+	if e, ok := service.AsError(err); ok {
+		body.Error.Code = e.Code
+		body.Error.Message = e.Message
+	} else {
+		fmt.Printf("API request failed: %v\n", err)
+		body.Error.Code = "internal"
+		body.Error.Message = "Internal server error"
+	}
+	writeJSON(w, service.StatusCode(err), body)
+}
+
+// decode parses the JSON request body into v
+func decode(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return service.Invalid("Invalid request body: %v", err)
+	}
+	return nil
+}
+
+// pathID parses the {id} wildcard of the request path
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, service.Invalid("Invalid ID")
+	}
+	return id, nil
+}
+
+// queryInt parses an optional integer query parameter
+func queryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, service.Invalid("Invalid %s", name)
+	}
+	return n, nil
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
+)
+
+// Client calls the API of a remote Figure10 server
+type Client struct {
+	BaseURL string // e.g. "http://localhost:8081"
+	Token   string
+	HTTP    *http.Client
+}
+
+// NewClient creates a client for the server at baseURL
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		HTTP:    &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+// Login exchanges a username and password for a token and uses it for
+// subsequent requests
+func (c *Client) Login(username, password string) (service.Login, error) {
+	var login service.Login
+	err := c.do(http.MethodPost, "/login", credentials{Username: username, Password: password}, &login)
+	if err != nil {
+		return service.Login{}, err
+	}
+
+	c.Token = login.Token
+	return login, nil
+}
+
+// GenerateText generates a text with the server's LLM provider
+func (c *Client) GenerateText(prompt string) (models.Text, error) {
+	var text models.Text
+	err := c.do(http.MethodPost, "/texts", createTextRequest{Source: sourceLLM, Prompt: prompt}, &text)
+	return text, err
+}
+
+// GenerateOffline generates a text with the server's offline generator
+func (c *Client) GenerateOffline() (models.Text, error) {
+	var text models.Text
+	err := c.do(http.MethodPost, "/texts", createTextRequest{Source: sourceOffline}, &text)
+	return text, err
+}
+
+// StartSession starts a typing session on a text
+func (c *Client) StartSession(textID int64) (models.Session, error) {
+	var response struct {
+		Session models.Session `json:"session"`
+	}
+	err := c.do(http.MethodPost, "/sessions", map[string]int64{"text_id": textID}, &response)
+	return response.Session, err
+}
+
+// RecordKeystrokes appends keystroke events to a session
+func (c *Client) RecordKeystrokes(sessionID int64, events []models.Keystroke) error {
+	body := map[string][]models.Keystroke{"events": events}
+	return c.do(http.MethodPost, fmt.Sprintf("/sessions/%d/keystrokes", sessionID), body, nil)
+}
+
+// SubmitSession completes a session and returns its score
+func (c *Client) SubmitSession(sessionID int64) (models.TypingResult, error) {
+	var result models.TypingResult
+	err := c.do(http.MethodPost, fmt.Sprintf("/sessions/%d/submit", sessionID), nil, &result)
+	return result, err
+}
+
+// do sends a request to the API and decodes the response into out. Error
+// responses are returned as *service.Error.
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+Prefix+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e errorBody
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error.Code == "" {
+			return fmt.Errorf("server returned %s", resp.Status)
+		}
+		return &service.Error{Code: e.Error.Code, Message: e.Error.Message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/janislaus/figure10/internal/importer"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
+)
+
+// handleImport imports an uploaded file as a collection. The file is sent as
+// the "file" field of a multipart form, with an optional "name".
+func (a *API) handleImport(w http.ResponseWriter, r *http.Request, user models.User) {
+	r.Body = http.MaxBytesReader(w, r.Body, importer.MaxFileSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, service.Invalid("Expected a multipart form with a file field: %v", err))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, service.Invalid("Failed to read file: %v", err))
+		return
+	}
+
+	collection, err := a.Service.ImportFile(user.ID, r.FormValue("name"), header.Filename, data)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, collection)
+}
+
+// handleListCollections returns the user's collections with their progress
+func (a *API) handleListCollections(w http.ResponseWriter, r *http.Request, user models.User) {
+	collections, err := a.Service.Collections(user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if collections == nil {
+		collections = []models.Collection{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"collections": collections})
+}
+
+// handleGetCollection returns a single collection
+func (a *API) handleGetCollection(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	collection, err := a.Service.Collection(user.ID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, collection)
+}
+
+// handleNextPassage returns the next passage of a collection to type
+func (a *API) handleNextPassage(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	text, err := a.Service.NextPassage(user.ID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, text)
+}
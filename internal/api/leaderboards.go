@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// handleBests returns the user's personal bests
+func (a *API) handleBests(w http.ResponseWriter, r *http.Request, user models.User) {
+	bests, err := a.Service.PersonalBests(user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if bests == nil {
+		bests = []models.PersonalBest{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"min_accuracy":   a.Service.MinAccuracy,
+		"personal_bests": bests,
+	})
+}
+
+// handleTextLeaderboard ranks the best session of each user on a text
+func (a *API) handleTextLeaderboard(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	entries, err := a.Service.TextLeaderboard(user.ID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []models.LeaderboardEntry{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"min_accuracy": a.Service.MinAccuracy,
+		"leaderboard":  entries,
+	})
+}
+
+// handleChallenge returns this week's challenge with its leaderboard
+func (a *API) handleChallenge(w http.ResponseWriter, r *http.Request, user models.User) {
+	challenge, err := a.Service.WeeklyChallenge(time.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if challenge.Leaderboard == nil {
+		challenge.Leaderboard = []models.LeaderboardEntry{}
+	}
+
+	writeJSON(w, http.StatusOK, challenge)
+}
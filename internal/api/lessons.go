@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// handleLessons returns the curriculum of the user's layout with the user's
+// progress. Lesson texts are generated with POST /texts.
+func (a *API) handleLessons(w http.ResponseWriter, r *http.Request, user models.User) {
+	settings, err := a.Service.Settings(user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	lessons, err := a.Service.Curriculum(user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"layout":  settings.Layout,
+		"lessons": lessons,
+	})
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
+)
+
+// handleLibrary searches the user's library. Filters are given as the query
+// parameters q, length, difficulty, source, tag and collection.
+func (a *API) handleLibrary(w http.ResponseWriter, r *http.Request, user models.User) {
+	values := r.URL.Query()
+	query := service.LibraryQuery{
+		Query:      values.Get("q"),
+		Length:     values.Get("length"),
+		Difficulty: values.Get("difficulty"),
+		Source:     values.Get("source"),
+		Tag:        values.Get("tag"),
+	}
+
+	if collection := values.Get("collection"); collection != "" {
+		id, err := strconv.ParseInt(collection, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, service.Invalid("Invalid collection"))
+			return
+		}
+		query.CollectionID = id
+	}
+
+	var err error
+	if query.Limit, err = queryInt(r, "limit"); err != nil {
+		writeError(w, err)
+		return
+	}
+	if query.Offset, err = queryInt(r, "offset"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	texts, err := a.Service.Library(user.ID, query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if texts == nil {
+		texts = []models.LibraryText{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"texts": texts})
+}
+
+// handleSetTags replaces the user's tags of a text
+func (a *API) handleSetTags(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var request struct {
+		Tags []string `json:"tags"`
+	}
+	if err := decode(r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	tags, err := a.Service.TagText(user.ID, id, request.Tags)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tags": tags})
+}
+
+// handleListTags returns the names of the user's tags
+func (a *API) handleListTags(w http.ResponseWriter, r *http.Request, user models.User) {
+	tags, err := a.Service.Tags(user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tags": tags})
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/janislaus/figure10/internal/analytics"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
+)
+
+// handleStartSession starts a typing session on a text. Without a test the
+// whole text is typed.
+func (a *API) handleStartSession(w http.ResponseWriter, r *http.Request, user models.User) {
+	var request struct {
+		TextID int64       `json:"text_id"`
+		Test   models.Test `json:"test"`
+	}
+	if err := decode(r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	session, text, err := a.Service.StartSession(user.ID, request.TextID, request.Test)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"session": session,
+		"text":    text,
+	})
+}
+
+// handleStartTest generates the text of a test. The test is passed on when
+// starting the session on the text.
+func (a *API) handleStartTest(w http.ResponseWriter, r *http.Request, user models.User) {
+	var test models.Test
+	if err := decode(r, &test); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	text, err := a.Service.StartTest(test)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"text": text})
+}
+
+// handleExtendText appends the next chunk of a timed or sudden death test to
+// the text of a session
+func (a *API) handleExtendText(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	more, err := a.Service.ExtendText(user.ID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"content": more})
+}
+
+// handleListSessions returns the user's most recent completed sessions
+func (a *API) handleListSessions(w http.ResponseWriter, r *http.Request, user models.User) {
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	sessions, err := a.Service.ListSessions(user.ID, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if sessions == nil {
+		sessions = []models.SessionWithText{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": sessions})
+}
+
+// handleGetSession returns a session with its text and errors, broken down
+// word by word and compared with the user's other sessions on the text
+func (a *API) handleGetSession(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	breakdown, err := a.Service.Breakdown(user.ID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, breakdown)
+}
+
+// handleReplay returns the keystroke log of a completed session prepared for
+// playback, with the speed in each second and the pauses
+func (a *API) handleReplay(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	replay, err := a.Service.Replay(user.ID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, replay)
+}
+
+// handleRecordKeystrokes appends a batch of keystroke events to a session
+func (a *API) handleRecordKeystrokes(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var request struct {
+		Events []models.Keystroke `json:"events"`
+	}
+	if err := decode(r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := a.Service.RecordKeystrokes(user.ID, id, request.Events); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"recorded": len(request.Events)})
+}
+
+// handleSubmitSession scores a session from its keystrokes and completes it
+func (a *API) handleSubmitSession(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	result, err := a.Service.SubmitSession(user.ID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleErrors returns the user's most common typing errors
+func (a *API) handleErrors(w http.ResponseWriter, r *http.Request, user models.User) {
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	errors, err := a.Service.CommonErrors(user.ID, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if errors == nil {
+		errors = []models.CommonError{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"errors": errors})
+}
+
+// keyHeatLevels is the number of levels of the error heatmap of the keys
+const keyHeatLevels = 5
+
+// handleKeyErrors returns the error rate of each key, highest first, and
+// its level in a heatmap of the keys
+func (a *API) handleKeyErrors(w http.ResponseWriter, r *http.Request, user models.User) {
+	rates, err := a.Service.KeyErrors(user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if rates == nil {
+		rates = []analytics.ErrorRate{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": rates,
+		"heat": analytics.ErrorHeatLevels(rates, keyHeatLevels),
+	})
+}
+
+// handleStats returns the user's summary statistics and latency analytics.
+// The latency tables are sorted by the sort query parameter.
+func (a *API) handleStats(w http.ResponseWriter, r *http.Request, user models.User) {
+	stats, err := a.Service.Stats(user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "p90"
+	}
+	report, err := a.Service.LatencyReport(user.ID, sortBy)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		models.Stats
+		Latency analytics.Report `json:"latency"`
+	}{stats, report})
+}
+
+// handleProgress returns the user's daily or weekly progress statistics,
+// selected by the period, from, to, mode and test query parameters
+func (a *API) handleProgress(w http.ResponseWriter, r *http.Request, user models.User) {
+	query := r.URL.Query()
+	report, err := a.Service.Progress(user.ID, service.ProgressQuery{
+		Period: query.Get("period"),
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Mode:   query.Get("mode"),
+		Test:   query.Get("test"),
+	}, time.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
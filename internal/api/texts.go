@@ -0,0 +1,204 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
+	"github.com/janislaus/figure10/internal/textgen"
+)
+
+// Text sources accepted by POST /texts
+const (
+	sourceCustom   = "custom"
+	sourceLLM      = "llm"
+	sourceOffline  = "offline"
+	sourcePractice = "practice"
+	sourceAdaptive = "adaptive"
+	sourceCode     = "code"
+	sourceLesson   = "lesson"
+)
+
+// createTextRequest is the body of POST /texts. Source defaults to "custom"
+// when content is given and to "llm" otherwise.
+type createTextRequest struct {
+	Source     string          `json:"source"`
+	Content    string          `json:"content"`    // custom
+	Prompt     string          `json:"prompt"`     // custom, llm
+	Difficulty string          `json:"difficulty"` // llm, a difficulty name or empty for any
+	Options    *offlineOptions `json:"options"`    // offline
+	Words      []string        `json:"words"`      // practice
+	Offline    bool            `json:"offline"`    // adaptive, lesson: skip the LLM
+	Language   string          `json:"language"`   // code, empty for any
+	Lesson     string          `json:"lesson"`     // lesson, empty for the next lesson
+}
+
+// offlineOptions are the offline generator knobs. Missing fields keep the
+// generator's defaults and a missing seed picks a random one.
+type offlineOptions struct {
+	Words       *int     `json:"words"`
+	Rank        *int     `json:"rank"`
+	Punctuation *float64 `json:"punctuation"` // 0 to 1
+	Capitalize  *bool    `json:"capitalize"`
+	Numbers     *bool    `json:"numbers"`
+	Seed        *int64   `json:"seed"`
+}
+
+// textgenOptions validates the knobs and applies them to the defaults
+func (o *offlineOptions) textgenOptions() (textgen.Options, error) {
+	opts := textgen.DefaultOptions(time.Now().UnixNano())
+	if o == nil {
+		return opts, nil
+	}
+
+	if o.Words != nil {
+		if *o.Words <= 0 || *o.Words > 1000 {
+			return opts, service.Invalid("options.words must be between 1 and 1000")
+		}
+		opts.Words = *o.Words
+	}
+	if o.Rank != nil {
+		valid := false
+		for _, rank := range textgen.VocabularyRanks {
+			valid = valid || rank == *o.Rank
+		}
+		if !valid {
+			return opts, service.Invalid("options.rank must be one of %v", textgen.VocabularyRanks)
+		}
+		opts.Rank = *o.Rank
+	}
+	if o.Punctuation != nil {
+		if *o.Punctuation < 0 || *o.Punctuation > 1 {
+			return opts, service.Invalid("options.punctuation must be between 0 and 1")
+		}
+		opts.Punctuation = *o.Punctuation
+	}
+	if o.Capitalize != nil {
+		opts.Capitalize = *o.Capitalize
+	}
+	if o.Numbers != nil {
+		opts.Numbers = *o.Numbers
+	}
+	if o.Seed != nil {
+		opts.Seed = *o.Seed
+	}
+
+	return opts, nil
+}
+
+// handleCreateText stores a custom text or generates a new one
+func (a *API) handleCreateText(w http.ResponseWriter, r *http.Request, user models.User) {
+	var request createTextRequest
+	if err := decode(r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	source := request.Source
+	if source == "" {
+		source = sourceLLM
+		if request.Content != "" {
+			source = sourceCustom
+		}
+	}
+
+	var text models.Text
+	var err error
+	switch source {
+	case sourceCustom:
+		text, err = a.Service.CreateText(user.ID, request.Content, request.Prompt)
+	case sourceLLM:
+		text, err = a.Service.GenerateText(r.Context(), user.ID, request.Prompt, request.Difficulty)
+	case sourceOffline:
+		var opts textgen.Options
+		opts, err = request.Options.textgenOptions()
+		if err == nil {
+			text, err = a.Service.GenerateOffline(opts)
+		}
+	case sourcePractice:
+		text, err = a.Service.GeneratePractice(r.Context(), user.ID, request.Words)
+	case sourceAdaptive:
+		text, err = a.Service.GenerateAdaptive(r.Context(), user.ID, request.Offline)
+	case sourceCode:
+		text, err = a.Service.GenerateCode(request.Language, time.Now().UnixNano())
+	case sourceLesson:
+		text, err = a.Service.GenerateLesson(r.Context(), user.ID, request.Lesson, request.Offline)
+	default:
+		err = service.Invalid("Unknown source %q", source)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, text)
+}
+
+// handleListTexts returns a page of texts, newest first
+func (a *API) handleListTexts(w http.ResponseWriter, r *http.Request, user models.User) {
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	offset, err := queryInt(r, "offset")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	texts, err := a.Service.ListTexts(user.ID, limit, offset)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if texts == nil {
+		texts = []models.Text{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"texts": texts})
+}
+
+// handleGetText returns a single text
+func (a *API) handleGetText(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	text, err := a.Service.GetText(user.ID, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, text)
+}
+
+// handleCheckText compares the input typed so far against a text
+func (a *API) handleCheckText(w http.ResponseWriter, r *http.Request, user models.User) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var request struct {
+		Input     string `json:"input"`
+		ElapsedMS int64  `json:"elapsed_ms"`
+	}
+	if err := decode(r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	check, err := a.Service.CheckTyping(user.ID, id, request.Input, time.Duration(request.ElapsedMS)*time.Millisecond)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, check)
+}
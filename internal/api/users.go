@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// credentials is the body of the register and login requests
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// handleRegister creates an account and logs it in
+func (a *API) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var request credentials
+	if err := decode(r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := a.Service.Register(strings.TrimSpace(request.Username), request.Password)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	login, err := a.Service.StartLogin(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, login)
+}
+
+// handleLogin issues a bearer token
+func (a *API) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var request credentials
+	if err := decode(r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	user, err := a.Service.Authenticate(strings.TrimSpace(request.Username), request.Password)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	login, err := a.Service.StartLogin(user)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, login)
+}
+
+// handleLogout revokes the token the request was made with
+func (a *API) handleLogout(w http.ResponseWriter, r *http.Request, user models.User) {
+	token, _ := r.Context().Value(tokenKey{}).(string)
+	if err := a.Service.Logout(token); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMe returns the authenticated user
+func (a *API) handleMe(w http.ResponseWriter, r *http.Request, user models.User) {
+	writeJSON(w, http.StatusOK, user)
+}
+
+// handleGetSettings returns the user's settings
+func (a *API) handleGetSettings(w http.ResponseWriter, r *http.Request, user models.User) {
+	settings, err := a.Service.Settings(user.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// handlePutSettings replaces the user's settings
+func (a *API) handlePutSettings(w http.ResponseWriter, r *http.Request, user models.User) {
+	var settings models.UserSettings
+	if err := decode(r, &settings); err != nil {
+		writeError(w, err)
+		return
+	}
+	settings.UserID = user.ID
+
+	if err := a.Service.SaveSettings(settings); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	a.handleGetSettings(w, r, user)
+}
@@ -0,0 +1,157 @@
+// Package curriculum orders the keys of a keyboard layout into lessons that
+// unlock a few keys at a time, starting on the home row, and builds practice
+// texts that only use the keys unlocked so far.
+package curriculum
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/janislaus/figure10/internal/layouts"
+)
+
+// TargetAccuracy is the accuracy in percent a session needs to pass a lesson
+const TargetAccuracy = 95.0
+
+// step unlocks the keys of some columns of a row. Columns are those of the
+// layouts package, so a step unlocks the same physical keys on every layout.
+type step struct {
+	slug      string
+	name      string
+	row       string
+	columns   []int // nil selects every key of the row not unlocked before
+	shift     bool  // unlocks the Shift variants of every key so far
+	targetWPM float64
+}
+
+// steps are the lessons of every layout, in order
+var steps = []step{
+	{slug: "home-index-middle", name: "Home row: index and middle fingers", row: layouts.RowHome, columns: []int{2, 3, 6, 7}, targetWPM: 12},
+	{slug: "home-ring-pinky", name: "Home row: ring fingers and pinkies", row: layouts.RowHome, columns: []int{0, 1, 8, 9}, targetWPM: 14},
+	{slug: "home-inner", name: "Home row: the keys between the hands", row: layouts.RowHome, columns: []int{4, 5}, targetWPM: 15},
+	{slug: "top-index-middle", name: "Top row: index and middle fingers", row: layouts.RowTop, columns: []int{2, 3, 6, 7}, targetWPM: 16},
+	{slug: "top-ring-pinky", name: "Top row: ring fingers and pinkies", row: layouts.RowTop, columns: []int{0, 1, 8, 9}, targetWPM: 17},
+	{slug: "top-inner", name: "Top row: the keys between the hands", row: layouts.RowTop, columns: []int{4, 5}, targetWPM: 18},
+	{slug: "bottom-index-middle", name: "Bottom row: index and middle fingers", row: layouts.RowBottom, columns: []int{2, 3, 6, 7}, targetWPM: 19},
+	{slug: "bottom-ring-pinky", name: "Bottom row: ring fingers and pinkies", row: layouts.RowBottom, columns: []int{-1, 0, 1, 8, 9}, targetWPM: 20},
+	{slug: "bottom-inner", name: "Bottom row: the keys between the hands", row: layouts.RowBottom, columns: []int{4, 5}, targetWPM: 20},
+	{slug: "shift", name: "Capital letters with Shift", shift: true, targetWPM: 20},
+	{slug: "numbers", name: "Number row", row: layouts.RowNumber, targetWPM: 18},
+	{slug: "outer", name: "The outer keys of the pinkies", targetWPM: 20},
+}
+
+// Lesson is a step of the curriculum of a layout
+type Lesson struct {
+	ID             string   `json:"id"` // the layout and the step, e.g. "colemak/home-inner"
+	Layout         string   `json:"layout"`
+	Number         int      `json:"number"` // 1-based position in the curriculum
+	Name           string   `json:"name"`
+	Keys           []string `json:"keys"`    // the characters the lesson unlocks
+	Allowed        []string `json:"allowed"` // every character unlocked up to the lesson
+	Capitals       bool     `json:"capitals"`
+	TargetWPM      float64  `json:"target_wpm"`
+	TargetAccuracy float64  `json:"target_accuracy"`
+}
+
+// Passes reports whether a session with the WPM and accuracy passes the
+// lesson
+func (l Lesson) Passes(wpm, accuracy float64) bool {
+	return wpm >= l.TargetWPM && accuracy >= l.TargetAccuracy
+}
+
+// position identifies a key by its row and column
+type position struct {
+	row    string
+	column int
+}
+
+// Lessons returns the curriculum of a layout. Steps that would unlock no
+// keys on the layout are skipped.
+func Lessons(layout *layouts.Layout) []Lesson {
+	var lessons []Lesson
+	var allowed []string
+	unlocked := map[position]bool{}
+	capitals := false
+
+	for _, step := range steps {
+		// The keys the step unlocks, by the character they type plainly
+		added := map[position]bool{}
+		if !step.shift {
+			for _, row := range layouts.Rows {
+				for _, char := range layout.Keys(row) {
+					key, _ := layout.Key(char)
+					pos := position{key.Row, key.Column}
+					if !unlocked[pos] && step.selects(key) {
+						added[pos] = true
+					}
+				}
+			}
+		}
+		capitals = capitals || step.shift
+
+		var keys []string
+		for _, char := range layout.Chars() {
+			key, _ := layout.Key(char)
+			pos := position{key.Row, key.Column}
+			if key.AltGr || strings.TrimSpace(char) == "" {
+				continue
+			}
+			if key.Shift && !capitals {
+				continue
+			}
+			if added[pos] || (step.shift && key.Shift && unlocked[pos]) {
+				keys = append(keys, char)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		for pos := range added {
+			unlocked[pos] = true
+		}
+		allowed = append(allowed, keys...)
+		lessons = append(lessons, Lesson{
+			ID:             layout.ID + "/" + step.slug,
+			Layout:         layout.ID,
+			Number:         len(lessons) + 1,
+			Name:           step.name,
+			Keys:           keys,
+			Allowed:        slices.Clone(allowed),
+			Capitals:       capitals,
+			TargetWPM:      step.targetWPM,
+			TargetAccuracy: TargetAccuracy,
+		})
+	}
+	return lessons
+}
+
+// selects reports whether the step unlocks a key, unless it was unlocked
+// before
+func (s step) selects(key layouts.Key) bool {
+	switch {
+	case s.row == "":
+		// Whatever is left on the letter rows
+		return key.Row != layouts.RowNumber && key.Row != layouts.RowSpace
+	case key.Row != s.row:
+		return false
+	case s.columns == nil:
+		return true
+	}
+	return slices.Contains(s.columns, key.Column)
+}
+
+// Find returns the lesson with an ID
+func Find(id string) (Lesson, bool) {
+	layoutID, _, _ := strings.Cut(id, "/")
+	layout, ok := layouts.Get(layoutID)
+	if !ok {
+		return Lesson{}, false
+	}
+	for _, lesson := range Lessons(layout) {
+		if lesson.ID == id {
+			return lesson, true
+		}
+	}
+	return Lesson{}, false
+}
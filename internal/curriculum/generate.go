@@ -0,0 +1,213 @@
+package curriculum
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// minWords is the number of words of the word list a lesson needs before
+// its texts are made of real words rather than letter groups
+const minWords = 30
+
+// Generate builds a practice text of about wordCount words for a lesson,
+// drawing real words from the word list that only use the unlocked keys.
+// Early lessons unlock too few letters to spell many words; their texts
+// mix in groups of the unlocked characters. Words and groups with the keys
+// of the lesson are picked more often.
+func Generate(lesson Lesson, words []string, wordCount int, seed int64) string {
+	if wordCount <= 0 {
+		return ""
+	}
+
+	allowed := lesson.allowedSet()
+	var candidates []string
+	var weights []float64
+	total := 0.0
+	fresher := 0
+	for _, word := range words {
+		if !typeable(word, allowed) {
+			continue
+		}
+		weight := 1.0
+		if containsAny(word, lesson.Keys) {
+			weight = 3
+			fresher++
+		}
+		candidates = append(candidates, word)
+		weights = append(weights, weight)
+		total += weight
+	}
+
+	// Letter groups are drawn half from the keys of the lesson and half from
+	// the lowercase characters unlocked so far
+	var pool []string
+	capitals := false
+	for _, char := range lesson.Allowed {
+		if !unicode.IsUpper([]rune(char)[0]) {
+			pool = append(pool, char)
+		}
+	}
+	for _, char := range lesson.Keys {
+		capitals = capitals || unicode.IsUpper([]rune(char)[0])
+	}
+
+	// Letter groups practice the keys of the lesson that words can't, like
+	// digits and symbols, and the Shift lesson capitalizes more words
+	groupRate := 1.0
+	if len(candidates) >= minWords {
+		groupRate = 0.2
+		if fresher == 0 {
+			groupRate = 0.4
+		}
+	}
+	capitalRate := 0.1
+	if capitals {
+		capitalRate = 0.4
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	sentences := allowed["."]
+	var text []string
+	sentenceLen := 0
+	for i := 0; i < wordCount; i++ {
+		var word string
+		if r.Float64() < groupRate {
+			word = group(r, pool, lesson.Keys)
+		} else {
+			word = pick(r, candidates, weights, total)
+		}
+
+		if lesson.Capitals && (sentenceLen == 0 || r.Float64() < capitalRate) {
+			if capital := capitalize(word); typeable(capital, allowed) {
+				word = capital
+			}
+		}
+		sentenceLen++
+		if sentences && (sentenceLen >= 6+r.Intn(6) || i == wordCount-1) {
+			word += "."
+			sentenceLen = 0
+		} else if allowed[","] && sentenceLen > 2 && r.Float64() < 0.1 {
+			word += ","
+		}
+		text = append(text, word)
+	}
+	return strings.Join(text, " ")
+}
+
+// Prompt builds an LLM prompt asking for a text that only uses the keys
+// unlocked up to the lesson
+func Prompt(lesson Lesson) string {
+	var letters, others []string
+	for _, char := range lesson.Allowed {
+		switch r := []rune(char)[0]; {
+		case unicode.IsUpper(r):
+		case unicode.IsLetter(r):
+			letters = append(letters, char)
+		default:
+			others = append(others, char)
+		}
+	}
+
+	prompt := fmt.Sprintf(
+		"Create a typing practice paragraph for a beginner that ONLY uses words spelled with the letters %s. "+
+			"Do not use any other letter, not even once. Prefer words with %s.",
+		strings.Join(letters, " "), strings.Join(lesson.Keys, " "))
+	if lesson.Capitals {
+		prompt += " Capital letters are allowed."
+	} else {
+		prompt += " Write everything in lowercase."
+	}
+	if len(others) > 0 {
+		prompt += " The only punctuation allowed is " + strings.Join(others, " ") + "."
+	} else {
+		prompt += " Do not use any punctuation."
+	}
+	return prompt
+}
+
+// allowedSet returns the characters the lesson allows, including space
+func (l Lesson) allowedSet() map[string]bool {
+	set := map[string]bool{" ": true}
+	for _, char := range l.Allowed {
+		set[char] = true
+	}
+	return set
+}
+
+// Typeable reports whether a text only uses the characters unlocked up to
+// the lesson
+func (l Lesson) Typeable(text string) bool {
+	return typeable(text, l.allowedSet())
+}
+
+// Filter drops the words of a text that use characters the lesson doesn't
+// allow
+func (l Lesson) Filter(text string) string {
+	allowed := l.allowedSet()
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if typeable(word, allowed) {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// typeable reports whether every character of s is in the set
+func typeable(s string, set map[string]bool) bool {
+	for _, r := range s {
+		if !set[string(r)] {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAny reports whether s contains one of the characters
+func containsAny(s string, chars []string) bool {
+	for _, char := range chars {
+		if strings.Contains(s, char) {
+			return true
+		}
+	}
+	return false
+}
+
+// group builds a letter group of two to five characters, about half of
+// them from fresh
+func group(r *rand.Rand, pool, fresh []string) string {
+	n := 2 + r.Intn(4)
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if r.Intn(2) == 0 {
+			b.WriteString(fresh[r.Intn(len(fresh))])
+		} else {
+			b.WriteString(pool[r.Intn(len(pool))])
+		}
+	}
+	return b.String()
+}
+
+// pick draws a word with probability proportional to its weight
+func pick(r *rand.Rand, words []string, weights []float64, total float64) string {
+	target := r.Float64() * total
+	for i, w := range weights {
+		target -= w
+		if target < 0 {
+			return words[i]
+		}
+	}
+	return words[len(words)-1]
+}
+
+// capitalize upper-cases the first letter of a word
+func capitalize(word string) string {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return word
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
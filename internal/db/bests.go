@@ -0,0 +1,180 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// timestampLayout is how SQLite's CURRENT_TIMESTAMP formats times, in UTC
+const timestampLayout = "2006-01-02 15:04:05"
+
+// BestQuery selects personal bests of a user. Only finished sessions with at
+// least MinAccuracy count.
+type BestQuery struct {
+	UserID         int64
+	Category       string      // one of the models.Best categories
+	Key            string      // only the best with this key, empty for all
+	Test           models.Test // only the bests of this test, all tests without a mode
+	MinAccuracy    float64
+	ExcludeSession int64 // ignore this session, to find the best before it
+}
+
+// bestKey returns the SQL expression grouping sessions into the keys of a
+// personal best category
+func bestKey(category string) (string, error) {
+	switch category {
+	case models.BestOverall:
+		return "''", nil
+	case models.BestText:
+		return "CAST(s.text_id AS TEXT)", nil
+	case models.BestMode:
+		return "t.source", nil
+	case models.BestLength:
+		var cases []string
+		for _, r := range models.TextLengths {
+			condition := fmt.Sprintf("LENGTH(t.content) >= %d", r.Min)
+			if r.Max > 0 {
+				condition += fmt.Sprintf(" AND LENGTH(t.content) <= %d", r.Max)
+			}
+			cases = append(cases, fmt.Sprintf("WHEN %s THEN '%s'", condition, r.Name))
+		}
+		return "CASE " + strings.Join(cases, " ") + " END", nil
+	}
+	return "", fmt.Errorf("unknown personal best category %q", category)
+}
+
+// GetPersonalBests returns the fastest session for each test and key of a
+// category. Ties in speed are broken by accuracy, then by who got there first.
+func GetPersonalBests(db *sql.DB, q BestQuery) ([]models.PersonalBest, error) {
+	key, err := bestKey(q.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT best_key, id, text_id, wpm, accuracy, completed_at, test_mode, test_param FROM (
+			SELECT ` + key + ` AS best_key, s.id, s.text_id, s.wpm, s.accuracy, s.completed_at, s.test_mode, s.test_param,
+				ROW_NUMBER() OVER (
+					PARTITION BY s.test_mode, s.test_param, ` + key + `
+					ORDER BY s.wpm DESC, s.accuracy DESC, s.completed_at ASC, s.id ASC
+				) AS position
+			FROM sessions s
+			JOIN texts t ON t.id = s.text_id
+			WHERE s.user_id = ? AND s.completed_at IS NOT NULL AND s.finished AND s.accuracy >= ? AND s.id != ?
+		)
+		WHERE position = 1
+	`
+	args := []interface{}{q.UserID, q.MinAccuracy, q.ExcludeSession}
+	if q.Key != "" {
+		query += " AND best_key = ?"
+		args = append(args, q.Key)
+	}
+	if q.Test.Mode != "" {
+		query += " AND test_mode = ? AND test_param = ?"
+		args = append(args, q.Test.Mode, q.Test.Param)
+	}
+	query += " ORDER BY wpm DESC"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bests []models.PersonalBest
+	for rows.Next() {
+		best := models.PersonalBest{Category: q.Category}
+		var completedAtStr string
+		err := rows.Scan(&best.Key, &best.SessionID, &best.TextID, &best.WPM, &best.Accuracy, &completedAtStr,
+			&best.Test.Mode, &best.Test.Param)
+		if err != nil {
+			return nil, err
+		}
+
+		best.CompletedAt = parseTimestamp(completedAtStr)
+		bests = append(bests, best)
+	}
+
+	return bests, rows.Err()
+}
+
+// LeaderboardQuery selects the sessions ranked on a leaderboard. Zero times
+// don't limit the range.
+type LeaderboardQuery struct {
+	TextID      int64
+	Since       time.Time
+	Until       time.Time
+	MinAccuracy float64
+	Limit       int
+}
+
+// GetLeaderboard ranks the best finished session of each user on a text.
+// Only sessions that typed the whole text are ranked. Ties in speed are
+// broken by accuracy, then by who got there first.
+func GetLeaderboard(db *sql.DB, q LeaderboardQuery) ([]models.LeaderboardEntry, error) {
+	conditions := "s.text_id = ? AND s.test_mode = ? AND s.completed_at IS NOT NULL AND s.finished AND s.accuracy >= ?"
+	args := []interface{}{q.TextID, models.TestText, q.MinAccuracy}
+	if !q.Since.IsZero() {
+		conditions += " AND s.completed_at >= ?"
+		args = append(args, q.Since.UTC().Format(timestampLayout))
+	}
+	if !q.Until.IsZero() {
+		conditions += " AND s.completed_at < ?"
+		args = append(args, q.Until.UTC().Format(timestampLayout))
+	}
+	args = append(args, q.Limit)
+
+	rows, err := db.Query(`
+		SELECT username, id, wpm, accuracy, completed_at FROM (
+			SELECT u.username, s.id, s.wpm, s.accuracy, s.completed_at,
+				ROW_NUMBER() OVER (
+					PARTITION BY s.user_id
+					ORDER BY s.wpm DESC, s.accuracy DESC, s.completed_at ASC, s.id ASC
+				) AS position
+			FROM sessions s
+			JOIN users u ON u.id = s.user_id
+			WHERE `+conditions+`
+		)
+		WHERE position = 1
+		ORDER BY wpm DESC, accuracy DESC, completed_at ASC, id ASC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.LeaderboardEntry
+	for rows.Next() {
+		entry := models.LeaderboardEntry{Rank: len(entries) + 1}
+		var completedAtStr string
+		err := rows.Scan(&entry.Username, &entry.SessionID, &entry.WPM, &entry.Accuracy, &completedAtStr)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.CompletedAt = parseTimestamp(completedAtStr)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetWeeklyChallenge returns the text of the challenge of a week
+func GetWeeklyChallenge(db *sql.DB, week string) (models.Text, error) {
+	return scanText(db.QueryRow(
+		"SELECT "+textColumns+" FROM texts WHERE id = (SELECT text_id FROM weekly_challenges WHERE week = ?)",
+		week,
+	))
+}
+
+// SaveWeeklyChallenge stores a text as the challenge of a week, unless the
+// week already has one
+func SaveWeeklyChallenge(db *sql.DB, week string, textID int64) error {
+	_, err := db.Exec("INSERT OR IGNORE INTO weekly_challenges (week, text_id) VALUES (?, ?)", week, textID)
+	return err
+}
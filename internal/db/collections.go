@@ -0,0 +1,110 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// CreateCollection saves a collection of a user together with its passages,
+// numbered from 1 in the given order. Only the content and difficulty of the
+// passages are used.
+func CreateCollection(db *sql.DB, collection models.Collection, passages []models.Text) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO collections (user_id, name, source, format) VALUES (?, ?, ?, ?)",
+		collection.UserID, collection.Name, collection.Source, collection.Format,
+	)
+	if err != nil {
+		return 0, err
+	}
+	collectionID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO texts (content, prompt, kind, source, difficulty, collection_id, passage)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for i, passage := range passages {
+		prompt := fmt.Sprintf("%s, passage %d of %d", collection.Name, i+1, len(passages))
+		_, err := stmt.Exec(passage.Content, prompt, models.TextKindProse, models.TextSourceImport, passage.Difficulty, collectionID, i+1)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return collectionID, tx.Commit()
+}
+
+// collectionQuery selects collections with their passage count and the last
+// passage the user completed
+const collectionQuery = `
+	SELECT c.id, c.user_id, c.name, c.source, c.format, c.created_at,
+		(SELECT COUNT(*) FROM texts t WHERE t.collection_id = c.id),
+		(SELECT COALESCE(MAX(t.passage), 0)
+			FROM sessions s
+			JOIN texts t ON s.text_id = t.id
+			WHERE t.collection_id = c.id AND s.user_id = c.user_id AND s.completed_at IS NOT NULL)
+	FROM collections c
+`
+
+// scanCollection reads a collection selected with collectionQuery
+func scanCollection(row rowScanner) (models.Collection, error) {
+	var c models.Collection
+	var createdAtStr string
+
+	err := row.Scan(&c.ID, &c.UserID, &c.Name, &c.Source, &c.Format, &createdAtStr, &c.Passages, &c.Completed)
+	if err != nil {
+		return models.Collection{}, err
+	}
+
+	c.CreatedAt = parseTimestamp(createdAtStr)
+	return c, nil
+}
+
+// GetCollections retrieves the collections of a user, newest first
+func GetCollections(db *sql.DB, userID int64) ([]models.Collection, error) {
+	rows, err := db.Query(collectionQuery+" WHERE c.user_id = ? ORDER BY c.id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []models.Collection
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		collections = append(collections, c)
+	}
+
+	return collections, rows.Err()
+}
+
+// GetCollection retrieves a collection of a user by its ID
+func GetCollection(db *sql.DB, userID, id int64) (models.Collection, error) {
+	return scanCollection(db.QueryRow(collectionQuery+" WHERE c.id = ? AND c.user_id = ?", id, userID))
+}
+
+// GetPassage retrieves a passage of a collection by its number
+func GetPassage(db *sql.DB, collectionID int64, passage int) (models.Text, error) {
+	return scanText(db.QueryRow(
+		"SELECT "+textColumns+" FROM texts WHERE collection_id = ? AND passage = ?",
+		collectionID, passage,
+	))
+}
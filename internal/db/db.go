@@ -2,62 +2,138 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/janislaus/figure10/internal/models"
 )
 
-// InitDB initializes the database schema
-func InitDB(db *sql.DB) error {
-	// Create texts table
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS texts (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			content TEXT NOT NULL,
-			prompt TEXT NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
+// parseTimestamp parses a timestamp column. The driver returns columns declared
+// as TIMESTAMP in RFC 3339 format, while computed values such as MAX(...) come
+// back in SQLite's own format.
+func parseTimestamp(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// SaveText saves a new text to the database. An empty kind is stored as prose.
+func SaveText(db *sql.DB, text models.Text) (int64, error) {
+	if text.Kind == "" {
+		text.Kind = models.TextKindProse
+	}
+	if text.Source == "" {
+		text.Source = models.TextSourceLLM
+	}
+
+	result, err := db.Exec(
+		"INSERT INTO texts (content, prompt, kind, language, source, difficulty, lesson, streamed) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		text.Content, text.Prompt, text.Kind, text.Language, text.Source, text.Difficulty, text.Lesson, text.Streamed,
+	)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	// Create sessions table
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS sessions (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			text_id INTEGER,
-			wpm REAL,
-			accuracy REAL,
-			errors INTEGER,
-			completed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (text_id) REFERENCES texts(id)
-		)
-	`)
+	return result.LastInsertId()
+}
+
+// textColumns are the columns read by scanText
+const textColumns = "id, content, prompt, kind, language, source, difficulty, collection_id, passage, lesson, streamed, created_at"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanText reads a text selected with textColumns
+func scanText(row rowScanner) (models.Text, error) {
+	var text models.Text
+	var collectionID, passage sql.NullInt64
+	var createdAtStr string
+
+	err := row.Scan(&text.ID, &text.Content, &text.Prompt, &text.Kind, &text.Language, &text.Source, &text.Difficulty, &collectionID, &passage, &text.Lesson, &text.Streamed, &createdAtStr)
 	if err != nil {
-		return err
+		return models.Text{}, err
 	}
 
-	// Create errors table to track specific errors
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS typing_errors (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			session_id INTEGER,
-			expected_char TEXT,
-			typed_char TEXT,
-			position INTEGER,
-			FOREIGN KEY (session_id) REFERENCES sessions(id)
-		)
-	`)
+	text.CollectionID = collectionID.Int64
+	text.Passage = int(passage.Int64)
+	text.CreatedAt = parseTimestamp(createdAtStr)
+	return text, nil
+}
+
+// GetTextByID retrieves a text by its ID
+func GetTextByID(db *sql.DB, id int64) (models.Text, error) {
+	return scanText(db.QueryRow("SELECT "+textColumns+" FROM texts WHERE id = ?", id))
+}
+
+// textVisible limits a query of texts to the ones a user may read. Passages
+// of imported collections belong to the owner of the collection, all other
+// texts are shared.
+const textVisible = "(collection_id IS NULL OR collection_id IN (SELECT id FROM collections WHERE user_id = ?))"
+
+// GetUserText retrieves a text by its ID if the user may read it
+func GetUserText(db *sql.DB, userID, id int64) (models.Text, error) {
+	return scanText(db.QueryRow("SELECT "+textColumns+" FROM texts WHERE id = ? AND "+textVisible, id, userID))
+}
 
+// AppendText adds content to the end of a text and updates its difficulty
+func AppendText(db *sql.DB, textID int64, content string, difficulty int) error {
+	_, err := db.Exec(
+		"UPDATE texts SET content = content || ?, difficulty = ? WHERE id = ?",
+		content, difficulty, textID,
+	)
 	return err
 }
 
-// SaveText saves a new text to the database
-func SaveText(db *sql.DB, content, prompt string) (int64, error) {
+// ForkText stores a copy of a text with content appended and moves a session
+// onto the copy, so the other sessions on the text keep it as they typed it.
+// The copy belongs to the same collection as the text. It returns the ID of
+// the copy.
+func ForkText(db *sql.DB, sessionID int64, text models.Text, content string, difficulty int) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO texts (content, prompt, kind, language, source, difficulty, collection_id, passage, lesson, streamed)
+			SELECT content || ?, prompt, kind, language, source, ?, collection_id, passage, lesson, streamed FROM texts WHERE id = ?`,
+		content, difficulty, text.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	textID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("UPDATE sessions SET text_id = ? WHERE id = ?", textID, sessionID); err != nil {
+		return 0, err
+	}
+
+	return textID, tx.Commit()
+}
+
+// CountTextSessions returns the number of sessions on a text, in progress or
+// completed
+func CountTextSessions(db *sql.DB, textID int64) (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sessions WHERE text_id = ?", textID).Scan(&count)
+	return count, err
+}
+
+// StartSession creates a typing session of a user that is still in progress.
+// Its results are filled in by CompleteSession.
+func StartSession(db *sql.DB, userID, textID int64, test models.Test, layout string) (int64, error) {
 	result, err := db.Exec(
-		"INSERT INTO texts (content, prompt) VALUES (?, ?)",
-		content, prompt,
+		"INSERT INTO sessions (user_id, text_id, test_mode, test_param, layout, completed_at) VALUES (?, ?, ?, ?, ?, NULL)",
+		userID, textID, test.Mode, test.Param, layout,
 	)
 	if err != nil {
 		return 0, err
@@ -66,55 +142,213 @@ func SaveText(db *sql.DB, content, prompt string) (int64, error) {
 	return result.LastInsertId()
 }
 
-// GetTextByID retrieves a text by its ID
-func GetTextByID(db *sql.DB, id int64) (models.Text, error) {
-	var text models.Text
-	var createdAtStr string
+// metricColumns are the session columns holding the typing test metrics
+const metricColumns = "raw_wpm, net_wpm, keystroke_accuracy, uncorrected_errors, consistency"
+
+// metricFields receives the metric columns of a session, which are NULL for
+// sessions that were never scored with them
+type metricFields struct {
+	rawWPM, netWPM, keystrokeAccuracy, consistency sql.NullFloat64
+	uncorrectedErrors                              sql.NullInt64
+}
+
+// dest returns the scan destinations in the order of metricColumns
+func (f *metricFields) dest() []interface{} {
+	return []interface{}{&f.rawWPM, &f.netWPM, &f.keystrokeAccuracy, &f.uncorrectedErrors, &f.consistency}
+}
+
+func (f *metricFields) metrics() models.Metrics {
+	return models.Metrics{
+		RawWPM:            f.rawWPM.Float64,
+		NetWPM:            f.netWPM.Float64,
+		KeystrokeAccuracy: f.keystrokeAccuracy.Float64,
+		UncorrectedErrors: int(f.uncorrectedErrors.Int64),
+		Consistency:       f.consistency.Float64,
+	}
+}
+
+// GetSessionByID retrieves a session of a user by its ID. CompletedAt is zero
+// for sessions that are still in progress.
+func GetSessionByID(db *sql.DB, userID, id int64) (models.Session, error) {
+	var session models.Session
+	var wpm, accuracy sql.NullFloat64
+	var errors sql.NullInt64
+	var completedAtStr sql.NullString
+	var metrics metricFields
 
+	dest := append([]interface{}{&session.ID, &session.UserID, &session.TextID, &wpm, &accuracy, &errors, &completedAtStr,
+		&session.Test.Mode, &session.Test.Param}, metrics.dest()...)
 	err := db.QueryRow(
-		"SELECT id, content, prompt, created_at FROM texts WHERE id = ?",
-		id,
-	).Scan(&text.ID, &text.Content, &text.Prompt, &createdAtStr)
+		"SELECT id, user_id, text_id, wpm, accuracy, errors, completed_at, test_mode, test_param, "+metricColumns+" FROM sessions WHERE id = ? AND user_id = ?",
+		id, userID,
+	).Scan(dest...)
 
 	if err != nil {
-		return models.Text{}, err
+		return models.Session{}, err
 	}
 
-	text.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
-	return text, nil
+	session.WPM = wpm.Float64
+	session.Accuracy = accuracy.Float64
+	session.Errors = int(errors.Int64)
+	session.Metrics = metrics.metrics()
+	if completedAtStr.Valid {
+		session.CompletedAt = parseTimestamp(completedAtStr.String)
+	}
+	return session, nil
 }
 
-// SaveSession saves a new typing session to the database
-func SaveSession(db *sql.DB, textID int64, wpm, accuracy float64, errors int) (int64, error) {
-	result, err := db.Exec(
-		"INSERT INTO sessions (text_id, wpm, accuracy, errors) VALUES (?, ?, ?, ?)",
-		textID, wpm, accuracy, errors,
-	)
+// CompleteSession stores the final results of a session that is in progress
+func CompleteSession(db *sql.DB, sessionID int64, result models.TypingResult) error {
+	res, err := db.Exec(`
+		UPDATE sessions
+		SET wpm = ?, accuracy = ?, errors = ?, finished = ?, completed_at = CURRENT_TIMESTAMP,
+			raw_wpm = ?, net_wpm = ?, keystroke_accuracy = ?, uncorrected_errors = ?, consistency = ?
+		WHERE id = ? AND completed_at IS NULL
+	`, result.WPM, result.Accuracy, result.Errors, result.Finished,
+		result.RawWPM, result.NetWPM, result.KeystrokeAccuracy, result.UncorrectedErrors, result.Consistency,
+		sessionID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("session %d is not in progress", sessionID)
+	}
+
+	return nil
+}
+
+// ScoreSessions computes the metrics of completed sessions that don't have
+// them yet from their keystroke logs and returns how many were scored.
+// Sessions without a keystroke log are left alone.
+func ScoreSessions(db *sql.DB, score func(text models.Text, keystrokes []models.Keystroke) models.Metrics) (int, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.text_id
+		FROM sessions s
+		WHERE s.completed_at IS NOT NULL AND s.raw_wpm IS NULL
+			AND EXISTS (SELECT 1 FROM keystrokes k WHERE k.session_id = s.id)
+	`)
 	if err != nil {
 		return 0, err
 	}
 
-	return result.LastInsertId()
+	sessions := map[int64]int64{}
+	for rows.Next() {
+		var id, textID int64
+		if err := rows.Scan(&id, &textID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		sessions[id] = textID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, textID := range sessions {
+		text, err := GetTextByID(db, textID)
+		if err != nil {
+			return 0, err
+		}
+		keystrokes, err := GetKeystrokes(db, id)
+		if err != nil {
+			return 0, err
+		}
+
+		m := score(text, keystrokes)
+		_, err = db.Exec(`
+			UPDATE sessions
+			SET raw_wpm = ?, net_wpm = ?, keystroke_accuracy = ?, uncorrected_errors = ?, consistency = ?
+			WHERE id = ?
+		`, m.RawWPM, m.NetWPM, m.KeystrokeAccuracy, m.UncorrectedErrors, m.Consistency, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
 }
 
-// SaveTypingError saves a typing error to the database
-func SaveTypingError(db *sql.DB, sessionID int64, expected, typed string, position int) error {
+// SaveKeystrokes appends keystroke events to a session. Events whose sequence
+// number was already stored are ignored, so clients can safely resend a batch.
+func SaveKeystrokes(db *sql.DB, sessionID int64, keystrokes []models.Keystroke) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO keystrokes (session_id, seq, key, timestamp_ms, position, is_backspace)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, k := range keystrokes {
+		if _, err := stmt.Exec(sessionID, k.Seq, k.Key, k.Timestamp, k.Position, k.Backspace); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetKeystrokes retrieves the keystroke log of a session in recorded order
+func GetKeystrokes(db *sql.DB, sessionID int64) ([]models.Keystroke, error) {
+	rows, err := db.Query(`
+		SELECT id, session_id, seq, key, timestamp_ms, position, is_backspace
+		FROM keystrokes
+		WHERE session_id = ?
+		ORDER BY seq
+	`, sessionID)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keystrokes []models.Keystroke
+	for rows.Next() {
+		var k models.Keystroke
+
+		err := rows.Scan(&k.ID, &k.SessionID, &k.Seq, &k.Key, &k.Timestamp, &k.Position, &k.Backspace)
+		if err != nil {
+			return nil, err
+		}
+
+		keystrokes = append(keystrokes, k)
+	}
+
+	return keystrokes, rows.Err()
+}
+
+// SaveTypingError saves a typing error of a user to the database
+func SaveTypingError(db *sql.DB, userID, sessionID int64, expected, typed string, position int) error {
 	_, err := db.Exec(
-		"INSERT INTO typing_errors (session_id, expected_char, typed_char, position) VALUES (?, ?, ?, ?)",
-		sessionID, expected, typed, position,
+		"INSERT INTO typing_errors (user_id, session_id, expected_char, typed_char, position) VALUES (?, ?, ?, ?, ?)",
+		userID, sessionID, expected, typed, position,
 	)
 	return err
 }
 
-// GetRecentSessions retrieves recent typing sessions
-func GetRecentSessions(db *sql.DB, limit int) ([]models.SessionWithText, error) {
+// GetRecentSessions retrieves the recent typing sessions of a user
+func GetRecentSessions(db *sql.DB, userID int64, limit int) ([]models.SessionWithText, error) {
 	rows, err := db.Query(`
-		SELECT s.id, s.text_id, s.wpm, s.accuracy, s.errors, s.completed_at, t.prompt 
+		SELECT s.id, s.user_id, s.text_id, s.wpm, s.accuracy, s.errors, s.completed_at, t.prompt, t.language,
+			s.test_mode, s.test_param, `+prefixed("s.", metricColumns)+`
 		FROM sessions s
 		JOIN texts t ON s.text_id = t.id
+		WHERE s.user_id = ? AND s.completed_at IS NOT NULL
 		ORDER BY s.completed_at DESC
 		LIMIT ?
-	`, limit)
+	`, userID, limit)
 
 	if err != nil {
 		return nil, err
@@ -125,37 +359,45 @@ func GetRecentSessions(db *sql.DB, limit int) ([]models.SessionWithText, error)
 	for rows.Next() {
 		var session models.SessionWithText
 		var completedAtStr string
+		var metrics metricFields
 
-		err := rows.Scan(
+		dest := []interface{}{
 			&session.ID,
+			&session.UserID,
 			&session.TextID,
 			&session.WPM,
 			&session.Accuracy,
 			&session.Errors,
 			&completedAtStr,
 			&session.Prompt,
-		)
+			&session.Language,
+			&session.Test.Mode,
+			&session.Test.Param,
+		}
+		err := rows.Scan(append(dest, metrics.dest()...)...)
 
 		if err != nil {
 			return nil, err
 		}
 
-		session.CompletedAt, _ = time.Parse("2006-01-02 15:04:05", completedAtStr)
+		session.CompletedAt = parseTimestamp(completedAtStr)
+		session.Metrics = metrics.metrics()
 		sessions = append(sessions, session)
 	}
 
 	return sessions, nil
 }
 
-// GetCommonErrors retrieves the most common typing errors
-func GetCommonErrors(db *sql.DB, limit int) ([]models.CommonError, error) {
+// GetCommonErrors retrieves the most common typing errors of a user
+func GetCommonErrors(db *sql.DB, userID int64, limit int) ([]models.CommonError, error) {
 	rows, err := db.Query(`
 		SELECT expected_char, typed_char, COUNT(*) as count
 		FROM typing_errors
+		WHERE user_id = ?
 		GROUP BY expected_char, typed_char
 		ORDER BY count DESC
 		LIMIT ?
-	`, limit)
+	`, userID, limit)
 
 	if err != nil {
 		return nil, err
@@ -176,3 +418,303 @@ func GetCommonErrors(db *sql.DB, limit int) ([]models.CommonError, error) {
 
 	return errors, nil
 }
+
+// GetLayoutErrors counts the typing errors of a user per expected character
+// and the keyboard layout of the session they were made in
+func GetLayoutErrors(db *sql.DB, userID int64) ([]models.LayoutError, error) {
+	rows, err := db.Query(`
+		SELECT s.layout, e.expected_char, COUNT(*)
+		FROM typing_errors e
+		JOIN sessions s ON s.id = e.session_id
+		WHERE e.user_id = ?
+		GROUP BY s.layout, e.expected_char
+	`, userID)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var errors []models.LayoutError
+	for rows.Next() {
+		var e models.LayoutError
+		if err := rows.Scan(&e.Layout, &e.ExpectedChar, &e.Count); err != nil {
+			return nil, err
+		}
+		errors = append(errors, e)
+	}
+
+	return errors, rows.Err()
+}
+
+// GetKeystrokeLogs retrieves the keystroke logs of a user's most recent
+// completed sessions, newest first
+func GetKeystrokeLogs(db *sql.DB, userID int64, limit int) ([]models.SessionKeystrokes, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.completed_at, s.layout, t.content, t.kind, k.id, k.seq, k.key, k.timestamp_ms, k.position, k.is_backspace
+		FROM (
+			SELECT id, text_id, completed_at, layout
+			FROM sessions
+			WHERE user_id = ? AND completed_at IS NOT NULL
+			ORDER BY completed_at DESC
+			LIMIT ?
+		) s
+		JOIN texts t ON s.text_id = t.id
+		JOIN keystrokes k ON k.session_id = s.id
+		ORDER BY s.completed_at DESC, s.id, k.seq
+	`, userID, limit)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []models.SessionKeystrokes
+	for rows.Next() {
+		var sessionID int64
+		var completedAtStr, layout, content, kind string
+		var k models.Keystroke
+
+		err := rows.Scan(&sessionID, &completedAtStr, &layout, &content, &kind, &k.ID, &k.Seq, &k.Key, &k.Timestamp, &k.Position, &k.Backspace)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(logs) == 0 || logs[len(logs)-1].SessionID != sessionID {
+			logs = append(logs, models.SessionKeystrokes{
+				SessionID:   sessionID,
+				Content:     content,
+				Kind:        kind,
+				Layout:      layout,
+				CompletedAt: parseTimestamp(completedAtStr),
+			})
+		}
+		k.SessionID = sessionID
+		current := &logs[len(logs)-1]
+		current.Keystrokes = append(current.Keystrokes, k)
+	}
+
+	return logs, rows.Err()
+}
+
+// ListTexts retrieves the texts a user may read, newest first
+func ListTexts(db *sql.DB, userID int64, limit, offset int) ([]models.Text, error) {
+	rows, err := db.Query(`
+		SELECT `+textColumns+`
+		FROM texts
+		WHERE `+textVisible+`
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var texts []models.Text
+	for rows.Next() {
+		text, err := scanText(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		texts = append(texts, text)
+	}
+
+	return texts, rows.Err()
+}
+
+// GetSessionErrors retrieves the typing errors of a session in text order
+func GetSessionErrors(db *sql.DB, userID, sessionID int64) ([]models.TypingError, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, session_id, expected_char, typed_char, position
+		FROM typing_errors
+		WHERE user_id = ? AND session_id = ?
+		ORDER BY position, id
+	`, userID, sessionID)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var errors []models.TypingError
+	for rows.Next() {
+		var e models.TypingError
+
+		err := rows.Scan(&e.ID, &e.UserID, &e.SessionID, &e.ExpectedChar, &e.TypedChar, &e.Position)
+		if err != nil {
+			return nil, err
+		}
+
+		errors = append(errors, e)
+	}
+
+	return errors, rows.Err()
+}
+
+// GetStats summarizes the completed sessions of a user
+func GetStats(db *sql.DB, userID int64) (models.Stats, error) {
+	var stats models.Stats
+	var avgWPM, bestWPM, avgAccuracy sql.NullFloat64
+	var totalErrors sql.NullInt64
+
+	err := db.QueryRow(`
+		SELECT COUNT(*), AVG(wpm), MAX(wpm), AVG(accuracy), SUM(errors)
+		FROM sessions
+		WHERE user_id = ? AND completed_at IS NOT NULL
+	`, userID).Scan(&stats.Sessions, &avgWPM, &bestWPM, &avgAccuracy, &totalErrors)
+
+	if err != nil {
+		return models.Stats{}, err
+	}
+
+	stats.AverageWPM = avgWPM.Float64
+	stats.BestWPM = bestWPM.Float64
+	stats.AverageAccuracy = avgAccuracy.Float64
+	stats.TotalErrors = int(totalErrors.Int64)
+
+	rows, err := db.Query(`
+		SELECT test_mode, test_param, COUNT(*), AVG(wpm), MAX(wpm), AVG(accuracy)
+		FROM sessions
+		WHERE user_id = ? AND completed_at IS NOT NULL
+		GROUP BY test_mode, test_param
+		ORDER BY COUNT(*) DESC, test_mode, test_param
+	`, userID)
+	if err != nil {
+		return models.Stats{}, err
+	}
+	defer rows.Close()
+
+	stats.Tests = []models.TestStats{}
+	for rows.Next() {
+		var t models.TestStats
+		var avgWPM, bestWPM, avgAccuracy sql.NullFloat64
+		err := rows.Scan(&t.Test.Mode, &t.Test.Param, &t.Sessions, &avgWPM, &bestWPM, &avgAccuracy)
+		if err != nil {
+			return models.Stats{}, err
+		}
+
+		t.AverageWPM = avgWPM.Float64
+		t.BestWPM = bestWPM.Float64
+		t.AverageAccuracy = avgAccuracy.Float64
+		stats.Tests = append(stats.Tests, t)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Stats{}, err
+	}
+
+	stats.Layouts, err = getLayoutStats(db, userID)
+	if err != nil {
+		return models.Stats{}, err
+	}
+	return stats, nil
+}
+
+// getLayoutStats summarizes the completed sessions of a user per keyboard
+// layout, in the order the layouts were first used
+func getLayoutStats(db *sql.DB, userID int64) ([]models.LayoutStats, error) {
+	rows, err := db.Query(`
+		SELECT layout, COUNT(*), AVG(wpm), MAX(wpm), AVG(accuracy), MIN(completed_at), MAX(completed_at)
+		FROM sessions
+		WHERE user_id = ? AND completed_at IS NOT NULL
+		GROUP BY layout
+		ORDER BY MIN(completed_at), MIN(id)
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.LayoutStats{}
+	for rows.Next() {
+		var l models.LayoutStats
+		var avgWPM, bestWPM, avgAccuracy sql.NullFloat64
+		var first, last string
+		err := rows.Scan(&l.Layout, &l.Sessions, &avgWPM, &bestWPM, &avgAccuracy, &first, &last)
+		if err != nil {
+			return nil, err
+		}
+
+		l.AverageWPM = avgWPM.Float64
+		l.BestWPM = bestWPM.Float64
+		l.AverageAccuracy = avgAccuracy.Float64
+		l.FirstSession = parseTimestamp(first)
+		l.LastSession = parseTimestamp(last)
+		result = append(result, l)
+	}
+
+	return result, rows.Err()
+}
+
+// GetTextStats summarizes the completed sessions of a user on a text with a
+// test, leaving out one session
+func GetTextStats(db *sql.DB, userID, textID int64, test models.Test, excludeSessionID int64) (models.TextStats, error) {
+	var stats models.TextStats
+	var avgWPM, bestWPM, avgAccuracy sql.NullFloat64
+
+	err := db.QueryRow(`
+		SELECT COUNT(*), AVG(wpm), MAX(wpm), AVG(accuracy)
+		FROM sessions
+		WHERE user_id = ? AND text_id = ? AND test_mode = ? AND test_param = ?
+			AND id != ? AND completed_at IS NOT NULL
+	`, userID, textID, test.Mode, test.Param, excludeSessionID).Scan(&stats.Sessions, &avgWPM, &bestWPM, &avgAccuracy)
+	if err != nil {
+		return models.TextStats{}, err
+	}
+
+	stats.AverageWPM = avgWPM.Float64
+	stats.BestWPM = bestWPM.Float64
+	stats.AverageAccuracy = avgAccuracy.Float64
+	return stats, nil
+}
+
+// GetSessionSamples retrieves the completed sessions of a user in a time
+// range for the progress statistics. The duration and the number of
+// characters typed are taken from the keystroke log. An empty mode selects
+// every text source and a test without a mode every test.
+func GetSessionSamples(db *sql.DB, userID int64, since, until time.Time, mode string, test models.Test) ([]models.SessionSample, error) {
+	query := `
+		SELECT s.completed_at, t.source, s.wpm, s.accuracy,
+			COALESCE((SELECT MAX(k.timestamp_ms) - MIN(k.timestamp_ms) FROM keystrokes k WHERE k.session_id = s.id), 0),
+			(SELECT COUNT(*) FROM keystrokes k WHERE k.session_id = s.id AND NOT k.is_backspace)
+		FROM sessions s
+		JOIN texts t ON t.id = s.text_id
+		WHERE s.user_id = ? AND s.completed_at IS NOT NULL AND s.completed_at >= ? AND s.completed_at < ?
+	`
+	args := []interface{}{userID, since.UTC().Format(timestampLayout), until.UTC().Format(timestampLayout)}
+	if mode != "" {
+		query += " AND t.source = ?"
+		args = append(args, mode)
+	}
+	if test.Mode != "" {
+		query += " AND s.test_mode = ? AND s.test_param = ?"
+		args = append(args, test.Mode, test.Param)
+	}
+	query += " ORDER BY s.completed_at"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []models.SessionSample
+	for rows.Next() {
+		var sample models.SessionSample
+		var completedAtStr string
+		var durationMS int64
+
+		err := rows.Scan(&completedAtStr, &sample.Mode, &sample.WPM, &sample.Accuracy, &durationMS, &sample.Characters)
+		if err != nil {
+			return nil, err
+		}
+
+		sample.CompletedAt = parseTimestamp(completedAtStr)
+		sample.Duration = time.Duration(durationMS) * time.Millisecond
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
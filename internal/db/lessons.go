@@ -0,0 +1,74 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// RecordLessonAttempt counts a completed session of a lesson and keeps the
+// best WPM and accuracy. A passing session marks the lesson as passed unless
+// it was passed before. It returns the progress after the attempt.
+func RecordLessonAttempt(db *sql.DB, userID int64, lesson string, wpm, accuracy float64, passed bool) (models.LessonProgress, error) {
+	_, err := db.Exec(`
+		INSERT INTO lesson_progress (user_id, lesson, attempts, best_wpm, best_accuracy, passed_at)
+		VALUES (?, ?, 1, ?, ?, CASE WHEN ? THEN CURRENT_TIMESTAMP END)
+		ON CONFLICT (user_id, lesson) DO UPDATE SET
+			attempts = attempts + 1,
+			best_wpm = MAX(best_wpm, excluded.best_wpm),
+			best_accuracy = MAX(best_accuracy, excluded.best_accuracy),
+			passed_at = COALESCE(passed_at, excluded.passed_at)
+	`, userID, lesson, wpm, accuracy, passed)
+	if err != nil {
+		return models.LessonProgress{}, err
+	}
+
+	return scanLessonProgress(db.QueryRow(`
+		SELECT `+lessonProgressColumns+`
+		FROM lesson_progress
+		WHERE user_id = ? AND lesson = ?
+	`, userID, lesson))
+}
+
+// GetLessonProgress retrieves a user's progress through every lesson the
+// user attempted, by lesson
+func GetLessonProgress(db *sql.DB, userID int64) (map[string]models.LessonProgress, error) {
+	rows, err := db.Query(`
+		SELECT `+lessonProgressColumns+`
+		FROM lesson_progress
+		WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := map[string]models.LessonProgress{}
+	for rows.Next() {
+		p, err := scanLessonProgress(rows)
+		if err != nil {
+			return nil, err
+		}
+		progress[p.Lesson] = p
+	}
+
+	return progress, rows.Err()
+}
+
+// lessonProgressColumns are the columns read by scanLessonProgress
+const lessonProgressColumns = "lesson, attempts, best_wpm, best_accuracy, passed_at"
+
+// scanLessonProgress reads lesson progress selected with
+// lessonProgressColumns
+func scanLessonProgress(row rowScanner) (models.LessonProgress, error) {
+	var p models.LessonProgress
+	var passedAt sql.NullString
+
+	err := row.Scan(&p.Lesson, &p.Attempts, &p.BestWPM, &p.BestAccuracy, &passedAt)
+	if err != nil {
+		return models.LessonProgress{}, err
+	}
+
+	p.PassedAt = parseTimestamp(passedAt.String)
+	return p, nil
+}
@@ -0,0 +1,278 @@
+package db
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// ftsSchema sets up the full-text index of texts. The index is kept in sync
+// by triggers and rebuilt on every start, so texts saved while full-text
+// search was unavailable are picked up.
+const ftsSchema = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS texts_fts USING fts5(content, prompt, content='texts', content_rowid='id');
+
+	CREATE TRIGGER IF NOT EXISTS texts_fts_insert AFTER INSERT ON texts BEGIN
+		INSERT INTO texts_fts (rowid, content, prompt) VALUES (new.id, new.content, new.prompt);
+	END;
+	CREATE TRIGGER IF NOT EXISTS texts_fts_delete AFTER DELETE ON texts BEGIN
+		INSERT INTO texts_fts (texts_fts, rowid, content, prompt) VALUES ('delete', old.id, old.content, old.prompt);
+	END;
+	CREATE TRIGGER IF NOT EXISTS texts_fts_update AFTER UPDATE ON texts BEGIN
+		INSERT INTO texts_fts (texts_fts, rowid, content, prompt) VALUES ('delete', old.id, old.content, old.prompt);
+		INSERT INTO texts_fts (rowid, content, prompt) VALUES (new.id, new.content, new.prompt);
+	END;
+
+	INSERT INTO texts_fts (texts_fts) VALUES ('rebuild');
+`
+
+// EnableFullTextSearch sets up the FTS5 index of texts and reports whether
+// it is available. SQLite only has FTS5 when built with the sqlite_fts5 tag;
+// without it the triggers are removed so texts can still be saved, and
+// searches fall back to LIKE.
+func EnableFullTextSearch(db *sql.DB) (bool, error) {
+	_, err := db.Exec(ftsSchema)
+	if err == nil {
+		return true, nil
+	}
+	if !strings.Contains(err.Error(), "no such module") {
+		return false, err
+	}
+
+	_, err = db.Exec(`
+		DROP TRIGGER IF EXISTS texts_fts_insert;
+		DROP TRIGGER IF EXISTS texts_fts_delete;
+		DROP TRIGGER IF EXISTS texts_fts_update;
+	`)
+	return false, err
+}
+
+// RateTexts sets the difficulty of texts that weren't rated yet and returns
+// how many were rated
+func RateTexts(db *sql.DB, rate func(content string) int) (int, error) {
+	rows, err := db.Query("SELECT id, content FROM texts WHERE difficulty = 0")
+	if err != nil {
+		return 0, err
+	}
+
+	ratings := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ratings[id] = rate(content)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for id, difficulty := range ratings {
+		if _, err := tx.Exec("UPDATE texts SET difficulty = ? WHERE id = ?", difficulty, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ratings), tx.Commit()
+}
+
+// LibraryFilter selects texts of a user's library. Zero values don't filter.
+type LibraryFilter struct {
+	UserID       int64
+	Query        string // words that must all appear in the content or prompt
+	FullText     bool   // search with the FTS5 index instead of LIKE
+	MinLength    int    // in characters
+	MaxLength    int    // in characters
+	Difficulty   int
+	Source       string
+	Tag          string
+	CollectionID int64
+	Limit        int
+	Offset       int
+}
+
+// SearchLibrary returns the texts matching the filter, newest first, with
+// the user's tags and typing history. Passages of other users' collections
+// are never included.
+func SearchLibrary(db *sql.DB, filter LibraryFilter) ([]models.LibraryText, error) {
+	query := `
+		SELECT ` + prefixed("t.", textColumns) + `,
+			(SELECT GROUP_CONCAT(g.name, ',') FROM text_tags tt JOIN tags g ON g.id = tt.tag_id
+				WHERE tt.text_id = t.id AND g.user_id = ?),
+			(SELECT COUNT(*) FROM sessions s
+				WHERE s.text_id = t.id AND s.user_id = ? AND s.completed_at IS NOT NULL),
+			(SELECT COALESCE(MAX(s.wpm), 0) FROM sessions s
+				WHERE s.text_id = t.id AND s.user_id = ? AND s.completed_at IS NOT NULL)
+		FROM texts t
+		WHERE (t.collection_id IS NULL OR t.collection_id IN (SELECT id FROM collections WHERE user_id = ?))
+	`
+	args := []interface{}{filter.UserID, filter.UserID, filter.UserID, filter.UserID}
+
+	if words := strings.Fields(filter.Query); len(words) > 0 {
+		if filter.FullText {
+			query += " AND t.id IN (SELECT rowid FROM texts_fts WHERE texts_fts MATCH ?)"
+			args = append(args, ftsQuery(words))
+		} else {
+			for _, word := range words {
+				pattern := "%" + likeEscaper.Replace(word) + "%"
+				query += ` AND (t.content LIKE ? ESCAPE '\' OR t.prompt LIKE ? ESCAPE '\')`
+				args = append(args, pattern, pattern)
+			}
+		}
+	}
+	if filter.MinLength > 0 {
+		query += " AND LENGTH(t.content) >= ?"
+		args = append(args, filter.MinLength)
+	}
+	if filter.MaxLength > 0 {
+		query += " AND LENGTH(t.content) <= ?"
+		args = append(args, filter.MaxLength)
+	}
+	if filter.Difficulty > 0 {
+		query += " AND t.difficulty = ?"
+		args = append(args, filter.Difficulty)
+	}
+	if filter.Source != "" {
+		query += " AND t.source = ?"
+		args = append(args, filter.Source)
+	}
+	if filter.Tag != "" {
+		query += ` AND t.id IN (SELECT tt.text_id FROM text_tags tt JOIN tags g ON g.id = tt.tag_id
+			WHERE g.user_id = ? AND g.name = ?)`
+		args = append(args, filter.UserID, filter.Tag)
+	}
+	if filter.CollectionID > 0 {
+		query += " AND t.collection_id = ?"
+		args = append(args, filter.CollectionID)
+	}
+	query += " ORDER BY t.id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var texts []models.LibraryText
+	for rows.Next() {
+		var text models.LibraryText
+		var collectionID, passage sql.NullInt64
+		var tags sql.NullString
+		var createdAtStr string
+
+		err := rows.Scan(&text.ID, &text.Content, &text.Prompt, &text.Kind, &text.Language, &text.Source, &text.Difficulty,
+			&collectionID, &passage, &text.Lesson, &text.Streamed, &createdAtStr, &tags, &text.Sessions, &text.BestWPM)
+		if err != nil {
+			return nil, err
+		}
+
+		text.CollectionID = collectionID.Int64
+		text.Passage = int(passage.Int64)
+		text.CreatedAt = parseTimestamp(createdAtStr)
+		text.Tags = []string{}
+		if tags.String != "" {
+			text.Tags = strings.Split(tags.String, ",")
+			slices.Sort(text.Tags)
+		}
+		texts = append(texts, text)
+	}
+
+	return texts, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards in a search word
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ftsQuery turns search words into an FTS5 query matching all of them. Each
+// word is quoted so its characters aren't read as query syntax, and the last
+// word matches as a prefix so results show up while typing.
+func ftsQuery(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	quoted[len(quoted)-1] += "*"
+	return strings.Join(quoted, " ")
+}
+
+// prefixed qualifies a comma separated column list with a table prefix
+func prefixed(prefix, columns string) string {
+	names := strings.Split(columns, ", ")
+	for i, name := range names {
+		names[i] = prefix + name
+	}
+	return strings.Join(names, ", ")
+}
+
+// SetTextTags replaces the tags a user gave a text. Tags no longer used on
+// any text are removed.
+func SetTextTags(db *sql.DB, userID, textID int64, tags []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		DELETE FROM text_tags
+		WHERE text_id = ? AND tag_id IN (SELECT id FROM tags WHERE user_id = ?)
+	`, textID, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		_, err := tx.Exec("INSERT OR IGNORE INTO tags (user_id, name) VALUES (?, ?)", userID, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			INSERT OR IGNORE INTO text_tags (tag_id, text_id)
+			SELECT id, ? FROM tags WHERE user_id = ? AND name = ?
+		`, textID, userID, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM tags
+		WHERE user_id = ? AND id NOT IN (SELECT tag_id FROM text_tags)
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetTags returns the names of a user's tags in alphabetical order
+func GetTags(db *sql.DB, userID int64) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM tags WHERE user_id = ? ORDER BY name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+
+	return tags, rows.Err()
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a numbered schema change embedded in the binary
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationState describes whether a migration has been applied
+type MigrationState struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrations returns the embedded migrations ordered by version. Files are
+// named NNNN_description.sql.
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := map[int]string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		versionStr, description, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %v", name, err)
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    description,
+			SQL:     string(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// ensureSchemaVersionTable creates the table recording applied migrations
+func ensureSchemaVersionTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// MigrationStatus lists every embedded migration and whether it was applied
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	if err := ensureSchemaVersionTable(db); err != nil {
+		return nil, err
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAtStr string
+		if err := rows.Scan(&version, &appliedAtStr); err != nil {
+			return nil, err
+		}
+		applied[version] = parseTimestamp(appliedAtStr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		states = append(states, MigrationState{
+			Migration: m,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return states, nil
+}
+
+// Migrate applies all pending migrations in order, each in its own
+// transaction, and returns how many were applied
+func Migrate(db *sql.DB) (int, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, state := range states {
+		if state.Applied {
+			continue
+		}
+		if err := applyMigration(db, state.Migration); err != nil {
+			return count, fmt.Errorf("migration %04d_%s: %v", state.Version, state.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// applyMigration runs a single migration and records it in schema_version
+func applyMigration(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_version (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
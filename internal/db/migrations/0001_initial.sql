@@ -0,0 +1,29 @@
+-- Initial schema. Uses IF NOT EXISTS so databases created before versioned
+-- migrations existed are adopted as they are.
+
+CREATE TABLE IF NOT EXISTS texts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	content TEXT NOT NULL,
+	prompt TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	text_id INTEGER,
+	wpm REAL,
+	accuracy REAL,
+	errors INTEGER,
+	completed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (text_id) REFERENCES texts(id)
+);
+
+-- Specific errors made during a session
+CREATE TABLE IF NOT EXISTS typing_errors (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INTEGER,
+	expected_char TEXT,
+	typed_char TEXT,
+	position INTEGER,
+	FOREIGN KEY (session_id) REFERENCES sessions(id)
+);
@@ -0,0 +1,12 @@
+-- Raw key events of each session
+CREATE TABLE keystrokes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INTEGER NOT NULL,
+	seq INTEGER NOT NULL,
+	key TEXT NOT NULL,
+	timestamp_ms INTEGER NOT NULL,
+	position INTEGER NOT NULL,
+	is_backspace BOOLEAN NOT NULL DEFAULT 0,
+	UNIQUE (session_id, seq),
+	FOREIGN KEY (session_id) REFERENCES sessions(id)
+);
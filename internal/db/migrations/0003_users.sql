@@ -0,0 +1,22 @@
+-- User accounts and login tokens
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL UNIQUE,
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE auth_sessions (
+	token TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Sessions and errors are owned by a user
+ALTER TABLE sessions ADD COLUMN user_id INTEGER REFERENCES users(id);
+ALTER TABLE typing_errors ADD COLUMN user_id INTEGER REFERENCES users(id);
+
+CREATE INDEX idx_sessions_user ON sessions(user_id, completed_at);
+CREATE INDEX idx_typing_errors_user ON typing_errors(user_id);
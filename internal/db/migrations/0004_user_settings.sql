@@ -0,0 +1,9 @@
+-- Per-user preferences, such as how generated text is normalized
+CREATE TABLE user_settings (
+	user_id INTEGER PRIMARY KEY,
+	strip_markdown BOOLEAN NOT NULL DEFAULT 1,
+	ascii_punctuation BOOLEAN NOT NULL DEFAULT 1,
+	max_length INTEGER NOT NULL DEFAULT 0,
+	allowed_chars TEXT NOT NULL DEFAULT '',
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,3 @@
+-- Texts are either prose or code. Code texts record their language.
+ALTER TABLE texts ADD COLUMN kind TEXT NOT NULL DEFAULT 'prose';
+ALTER TABLE texts ADD COLUMN language TEXT NOT NULL DEFAULT '';
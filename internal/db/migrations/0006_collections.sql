@@ -0,0 +1,16 @@
+-- Imported files are stored as collections of passages in reading order
+CREATE TABLE collections (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	source TEXT NOT NULL,
+	format TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+ALTER TABLE texts ADD COLUMN collection_id INTEGER REFERENCES collections(id);
+ALTER TABLE texts ADD COLUMN passage INTEGER;
+
+CREATE INDEX idx_texts_collection ON texts(collection_id, passage);
+CREATE INDEX idx_collections_user ON collections(user_id);
@@ -0,0 +1,35 @@
+-- Texts record how they were made and how hard they are. Difficulty 0 means
+-- not rated yet; existing texts are rated by the server on startup.
+ALTER TABLE texts ADD COLUMN source TEXT NOT NULL DEFAULT 'llm';
+ALTER TABLE texts ADD COLUMN difficulty INTEGER NOT NULL DEFAULT 0;
+
+UPDATE texts SET source = CASE
+	WHEN collection_id IS NOT NULL THEN 'import'
+	WHEN kind = 'code' THEN 'code'
+	WHEN prompt LIKE 'Offline:%' THEN 'offline'
+	WHEN prompt LIKE 'Practice:%' THEN 'practice'
+	WHEN prompt LIKE 'Adaptive drill%' THEN 'adaptive'
+	WHEN prompt = 'Custom text' THEN 'custom'
+	ELSE 'llm'
+END;
+
+CREATE INDEX idx_texts_source ON texts(source);
+
+-- Users tag texts to organize their library
+CREATE TABLE tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id),
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE text_tags (
+	tag_id INTEGER NOT NULL,
+	text_id INTEGER NOT NULL,
+	PRIMARY KEY (tag_id, text_id),
+	FOREIGN KEY (tag_id) REFERENCES tags(id),
+	FOREIGN KEY (text_id) REFERENCES texts(id)
+);
+
+CREATE INDEX idx_text_tags_text ON text_tags(text_id);
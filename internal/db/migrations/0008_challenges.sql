@@ -0,0 +1,13 @@
+-- One challenge text per ISO week, shared by all users
+CREATE TABLE weekly_challenges (
+	week TEXT PRIMARY KEY,
+	text_id INTEGER NOT NULL,
+	FOREIGN KEY (text_id) REFERENCES texts(id)
+);
+
+-- Leaderboards rank the sessions of a text across users
+CREATE INDEX idx_sessions_text ON sessions(text_id, completed_at);
+
+-- Only sessions that typed the whole text count for personal bests and
+-- leaderboards. Earlier sessions can't be told apart and count as finished.
+ALTER TABLE sessions ADD COLUMN finished BOOLEAN NOT NULL DEFAULT 1;
@@ -0,0 +1,8 @@
+-- Standard typing test metrics, computed from the keystroke log. They are
+-- NULL for sessions completed before they existed until the sessions are
+-- rescored, and stay NULL for sessions without a keystroke log.
+ALTER TABLE sessions ADD COLUMN raw_wpm REAL;
+ALTER TABLE sessions ADD COLUMN net_wpm REAL;
+ALTER TABLE sessions ADD COLUMN keystroke_accuracy REAL;
+ALTER TABLE sessions ADD COLUMN uncorrected_errors INTEGER;
+ALTER TABLE sessions ADD COLUMN consistency REAL;
@@ -0,0 +1,4 @@
+-- The test mode of a session and its parameter, the time limit in seconds
+-- or the number of words. Earlier sessions typed the whole text.
+ALTER TABLE sessions ADD COLUMN test_mode TEXT NOT NULL DEFAULT 'text';
+ALTER TABLE sessions ADD COLUMN test_param INTEGER NOT NULL DEFAULT 0;
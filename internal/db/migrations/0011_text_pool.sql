@@ -0,0 +1,12 @@
+-- Generated texts kept ready for generic prompts, by category and the
+-- difficulty they were generated for. A text leaves the pool when it is
+-- served and is stored in texts then.
+CREATE TABLE text_pool (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	category TEXT NOT NULL,
+	difficulty INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_text_pool_bucket ON text_pool(category, difficulty, id);
@@ -0,0 +1,4 @@
+-- The keyboard layout a user types on, and the layout each session was
+-- typed on. Earlier sessions were typed on the default layout.
+ALTER TABLE user_settings ADD COLUMN layout TEXT NOT NULL DEFAULT 'qwerty';
+ALTER TABLE sessions ADD COLUMN layout TEXT NOT NULL DEFAULT 'qwerty';
@@ -0,0 +1,15 @@
+-- Texts generated for a lesson of the curriculum, and each user's progress
+-- through the lessons. Lessons are identified by their layout and step,
+-- e.g. "colemak/home-inner".
+ALTER TABLE texts ADD COLUMN lesson TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE lesson_progress (
+	user_id INTEGER NOT NULL,
+	lesson TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	best_wpm REAL NOT NULL DEFAULT 0,
+	best_accuracy REAL NOT NULL DEFAULT 0,
+	passed_at TIMESTAMP,
+	PRIMARY KEY (user_id, lesson),
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,2 @@
+-- Whether the typing page shows the on-screen keyboard
+ALTER TABLE user_settings ADD COLUMN show_keyboard BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- The source a pooled text is stored with once it is served, so texts of
+-- the fallback provider aren't recorded as LLM texts
+ALTER TABLE text_pool ADD COLUMN source TEXT NOT NULL DEFAULT 'llm';
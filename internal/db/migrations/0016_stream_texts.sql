@@ -0,0 +1,3 @@
+-- Texts generated for a timed or sudden death test, which grow while they
+-- are typed. Only these texts may be extended.
+ALTER TABLE texts ADD COLUMN streamed BOOLEAN NOT NULL DEFAULT 0;
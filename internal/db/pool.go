@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+)
+
+// AddPoolText adds a generated text to the pool of a category and
+// difficulty, along with the source it is stored with once served
+func AddPoolText(db *sql.DB, category string, difficulty int, content, source string) error {
+	_, err := db.Exec(
+		"INSERT INTO text_pool (category, difficulty, content, source) VALUES (?, ?, ?, ?)",
+		category, difficulty, content, source,
+	)
+	return err
+}
+
+// TakePoolText removes the oldest text of a category from the pool and
+// returns its content and source. A zero difficulty takes a text of any
+// difficulty. It returns sql.ErrNoRows when the pool has no such text.
+func TakePoolText(db *sql.DB, category string, difficulty int) (string, string, error) {
+	// Concurrent requests may select the same text, only the one that
+	// deletes it gets it
+	for {
+		var id int64
+		var content, source string
+		err := db.QueryRow(`
+			SELECT id, content, source FROM text_pool
+			WHERE category = ? AND (? = 0 OR difficulty = ?)
+			ORDER BY id LIMIT 1
+		`, category, difficulty, difficulty).Scan(&id, &content, &source)
+		if err != nil {
+			return "", "", err
+		}
+
+		result, err := db.Exec("DELETE FROM text_pool WHERE id = ?", id)
+		if err != nil {
+			return "", "", err
+		}
+		if deleted, err := result.RowsAffected(); err != nil {
+			return "", "", err
+		} else if deleted == 1 {
+			return content, source, nil
+		}
+	}
+}
+
+// CountPoolTexts returns how many texts the pool holds for every category
+// and difficulty, keyed by category and then difficulty
+func CountPoolTexts(db *sql.DB) (map[string]map[int]int, error) {
+	rows, err := db.Query("SELECT category, difficulty, COUNT(*) FROM text_pool GROUP BY category, difficulty")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]map[int]int{}
+	for rows.Next() {
+		var category string
+		var difficulty, count int
+		if err := rows.Scan(&category, &difficulty, &count); err != nil {
+			return nil, err
+		}
+		if counts[category] == nil {
+			counts[category] = map[int]int{}
+		}
+		counts[category][difficulty] = count
+	}
+
+	return counts, rows.Err()
+}
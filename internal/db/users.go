@@ -0,0 +1,155 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/janislaus/figure10/internal/layouts"
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// CreateUser saves a new user with an already hashed password
+func CreateUser(db *sql.DB, username, passwordHash string) (int64, error) {
+	result, err := db.Exec(
+		"INSERT INTO users (username, password_hash) VALUES (?, ?)",
+		username, passwordHash,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// CountUsers returns the number of user accounts
+func CountUsers(db *sql.DB) (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
+	return count, err
+}
+
+// CountOrphanedSessions returns the number of sessions without an owner,
+// which were typed before there were user accounts
+func CountOrphanedSessions(db *sql.DB) (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sessions WHERE user_id IS NULL").Scan(&count)
+	return count, err
+}
+
+// AdoptOrphanedSessions gives the sessions without an owner and their errors
+// to a user and returns the number of sessions adopted
+func AdoptOrphanedSessions(db *sql.DB, userID int64) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE sessions SET user_id = ? WHERE user_id IS NULL", userID)
+	if err != nil {
+		return 0, err
+	}
+	adopted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("UPDATE typing_errors SET user_id = ? WHERE user_id IS NULL", userID); err != nil {
+		return 0, err
+	}
+
+	return adopted, tx.Commit()
+}
+
+// GetUserByUsername retrieves a user by their username
+func GetUserByUsername(db *sql.DB, username string) (models.User, error) {
+	return scanUser(db.QueryRow(
+		"SELECT id, username, password_hash, created_at FROM users WHERE username = ?",
+		username,
+	))
+}
+
+// GetUserByID retrieves a user by their ID
+func GetUserByID(db *sql.DB, id int64) (models.User, error) {
+	return scanUser(db.QueryRow(
+		"SELECT id, username, password_hash, created_at FROM users WHERE id = ?",
+		id,
+	))
+}
+
+// CreateAuthSession stores a login token for a user
+func CreateAuthSession(db *sql.DB, token string, userID int64, expiresAt time.Time) error {
+	_, err := db.Exec(
+		"INSERT INTO auth_sessions (token, user_id, expires_at) VALUES (?, ?, ?)",
+		token, userID, expiresAt.UTC().Format("2006-01-02 15:04:05"),
+	)
+	return err
+}
+
+// GetUserByAuthToken retrieves the user a login token belongs to, as long as
+// the token has not expired
+func GetUserByAuthToken(db *sql.DB, token string) (models.User, error) {
+	return scanUser(db.QueryRow(`
+		SELECT u.id, u.username, u.password_hash, u.created_at
+		FROM auth_sessions a
+		JOIN users u ON a.user_id = u.id
+		WHERE a.token = ? AND a.expires_at > CURRENT_TIMESTAMP
+	`, token))
+}
+
+// DeleteAuthSession removes a login token
+func DeleteAuthSession(db *sql.DB, token string) error {
+	_, err := db.Exec("DELETE FROM auth_sessions WHERE token = ?", token)
+	return err
+}
+
+// scanUser scans a single user row
+func scanUser(row *sql.Row) (models.User, error) {
+	var user models.User
+	var createdAtStr string
+
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &createdAtStr)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user.CreatedAt = parseTimestamp(createdAtStr)
+	return user, nil
+}
+
+// GetUserSettings retrieves a user's settings, falling back to the defaults
+// for users who never saved any
+func GetUserSettings(db *sql.DB, userID int64) (models.UserSettings, error) {
+	settings := models.UserSettings{
+		UserID:           userID,
+		StripMarkdown:    true,
+		ASCIIPunctuation: true,
+		Layout:           layouts.Default,
+	}
+
+	err := db.QueryRow(`
+		SELECT strip_markdown, ascii_punctuation, max_length, allowed_chars, layout, show_keyboard
+		FROM user_settings
+		WHERE user_id = ?
+	`, userID).Scan(&settings.StripMarkdown, &settings.ASCIIPunctuation, &settings.MaxLength, &settings.AllowedChars, &settings.Layout, &settings.ShowKeyboard)
+
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	return settings, err
+}
+
+// SaveUserSettings stores a user's settings
+func SaveUserSettings(db *sql.DB, settings models.UserSettings) error {
+	_, err := db.Exec(`
+		INSERT INTO user_settings (user_id, strip_markdown, ascii_punctuation, max_length, allowed_chars, layout, show_keyboard)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			strip_markdown = excluded.strip_markdown,
+			ascii_punctuation = excluded.ascii_punctuation,
+			max_length = excluded.max_length,
+			allowed_chars = excluded.allowed_chars,
+			layout = excluded.layout,
+			show_keyboard = excluded.show_keyboard
+	`, settings.UserID, settings.StripMarkdown, settings.ASCIIPunctuation, settings.MaxLength, settings.AllowedChars, settings.Layout, settings.ShowKeyboard)
+	return err
+}
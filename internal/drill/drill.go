@@ -0,0 +1,184 @@
+package drill
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"unicode"
+
+	"github.com/janislaus/figure10/internal/analytics"
+)
+
+// Target is a character or bigram the drill should emphasize
+type Target struct {
+	Key    string
+	Weight float64
+}
+
+// Profile describes a user's weaknesses
+type Profile struct {
+	Chars   []Target
+	Bigrams []Target
+}
+
+// Empty reports whether the profile has no weaknesses to train
+func (p Profile) Empty() bool {
+	return len(p.Chars) == 0 && len(p.Bigrams) == 0
+}
+
+// BuildProfile picks up to n of the most error-prone letters and n of the
+// slowest bigrams. Whitespace and punctuation are skipped because words
+// can't be chosen to practice them.
+func BuildProfile(errorRates []analytics.ErrorRate, bigrams []analytics.Latency, n int) Profile {
+	var profile Profile
+
+	for _, e := range errorRates {
+		if len(profile.Chars) >= n {
+			break
+		}
+		if e.Rate == 0 || !isLetters(e.Char) {
+			continue
+		}
+		profile.Chars = append(profile.Chars, Target{
+			Key:    strings.ToLower(e.Char),
+			Weight: e.Rate,
+		})
+	}
+
+	// Bigrams arrive sorted slowest first; weight them relative to the slowest
+	for _, b := range bigrams {
+		if len(profile.Bigrams) >= n {
+			break
+		}
+		if !isLetters(b.Key) || b.P90 <= 0 {
+			continue
+		}
+		profile.Bigrams = append(profile.Bigrams, Target{
+			Key:    strings.ToLower(b.Key),
+			Weight: b.P90 / bigrams[0].P90,
+		})
+	}
+
+	return profile
+}
+
+// Prompt builds an LLM prompt asking for a text that trains the profile
+func Prompt(profile Profile) string {
+	if profile.Empty() {
+		return "Give me a general typing practice text"
+	}
+
+	var parts []string
+	if len(profile.Chars) > 0 {
+		parts = append(parts, "the letters "+quoteKeys(profile.Chars))
+	}
+	if len(profile.Bigrams) > 0 {
+		parts = append(parts, "the letter pairs "+quoteKeys(profile.Bigrams))
+	}
+
+	return fmt.Sprintf(
+		"Create a typing practice paragraph that uses many words containing %s. "+
+			"Use these as often as possible while keeping the sentences natural. "+
+			"The earlier a letter or pair is listed, the more often it should appear.",
+		strings.Join(parts, " and "))
+}
+
+// Generate builds a deterministic practice text of about the given number of
+// words, drawing from the word list with a bias toward words that contain
+// the profile's weak letters and bigrams
+func Generate(profile Profile, words []string, wordCount int, seed int64) string {
+	if len(words) == 0 || wordCount <= 0 {
+		return ""
+	}
+
+	// Every word keeps a small base weight so the text stays varied
+	weights := make([]float64, len(words))
+	total := 0.0
+	for i, w := range words {
+		weights[i] = 0.05 + score(profile, w)
+		total += weights[i]
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	var text strings.Builder
+	sentenceLen := 0
+	sentenceTarget := 6 + r.Intn(6)
+	for i := 0; i < wordCount; i++ {
+		word := pick(r, words, weights, total)
+
+		if sentenceLen == 0 {
+			word = capitalize(word)
+			if i > 0 {
+				text.WriteString(" ")
+			}
+		} else {
+			text.WriteString(" ")
+		}
+		text.WriteString(word)
+		sentenceLen++
+
+		if sentenceLen >= sentenceTarget || i == wordCount-1 {
+			text.WriteString(".")
+			sentenceLen = 0
+			sentenceTarget = 6 + r.Intn(6)
+		}
+	}
+
+	return text.String()
+}
+
+// score rates how much typing the word trains the profile
+func score(profile Profile, word string) float64 {
+	s := 0.0
+	for _, c := range profile.Chars {
+		s += c.Weight * float64(strings.Count(word, c.Key))
+	}
+	for _, b := range profile.Bigrams {
+		s += 2 * b.Weight * float64(strings.Count(word, b.Key))
+	}
+	return s
+}
+
+// pick draws a word with probability proportional to its weight
+func pick(r *rand.Rand, words []string, weights []float64, total float64) string {
+	target := r.Float64() * total
+	for i, w := range weights {
+		target -= w
+		if target < 0 {
+			return words[i]
+		}
+	}
+	return words[len(words)-1]
+}
+
+// quoteKeys formats targets as a quoted, comma-separated list
+func quoteKeys(targets []Target) string {
+	quoted := make([]string, len(targets))
+	for i, t := range targets {
+		quoted[i] = fmt.Sprintf("%q", t.Key)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// isLetters reports whether s consists only of letters
+func isLetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// capitalize upper-cases the first letter of a word
+func capitalize(word string) string {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return word
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/janislaus/figure10/internal/auth"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
+	"github.com/janislaus/figure10/web/templates"
+)
+
+// contextKey is the type of the keys stored in a request context
+type contextKey string
+
+// userKey is the request context key of the logged-in user
+const userKey contextKey = "user"
+
+// currentUser returns the logged-in user of a request wrapped by RequireUser
+func currentUser(r *http.Request) models.User {
+	user, _ := r.Context().Value(userKey).(models.User)
+	return user
+}
+
+// RequireUser wraps a handler so it only runs for logged-in users. Page
+// requests are redirected to the login page, everything else gets a 401.
+func (h *Handler) RequireUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.userFromCookie(r)
+		if err != nil {
+			if r.Method == http.MethodGet && r.Header.Get("HX-Request") == "" {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			http.Error(w, "Not logged in", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userKey, user)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// userFromCookie looks up the user belonging to the request's login cookie
+func (h *Handler) userFromCookie(r *http.Request) (models.User, error) {
+	cookie, err := r.Cookie(auth.CookieName)
+	if err != nil {
+		return models.User{}, err
+	}
+	return h.Service.UserByToken(cookie.Value)
+}
+
+// HandleLogin renders the login page and logs users in
+func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		templates.Base(models.User{}, templates.Login("")).Render(context.Background(), w)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := strings.TrimSpace(r.FormValue("username"))
+	password := r.FormValue("password")
+
+	user, err := h.Service.Authenticate(username, password)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		templates.Base(models.User{}, templates.Login(err.Error())).Render(context.Background(), w)
+		return
+	}
+
+	if err := h.startLogin(w, user); err != nil {
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// HandleRegister renders the registration page and creates new accounts
+func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		templates.Base(models.User{}, templates.Register("")).Render(context.Background(), w)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := strings.TrimSpace(r.FormValue("username"))
+	password := r.FormValue("password")
+
+	user, err := h.Service.Register(username, password)
+	if _, ok := service.AsError(err); ok {
+		w.WriteHeader(service.StatusCode(err))
+		templates.Base(models.User{}, templates.Register(err.Error())).Render(context.Background(), w)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.startLogin(w, user); err != nil {
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// HandleLogout ends the current login
+func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if cookie, err := r.Cookie(auth.CookieName); err == nil {
+		h.Service.Logout(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// startLogin creates a login token for the user and sets it as a cookie
+func (h *Handler) startLogin(w http.ResponseWriter, user models.User) error {
+	login, err := h.Service.StartLogin(user)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    login.Token,
+		Path:     "/",
+		Expires:  login.ExpiresAt,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
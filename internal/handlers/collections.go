@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/janislaus/figure10/internal/importer"
+	"github.com/janislaus/figure10/web/templates"
+)
+
+// HandleImport renders the import page and imports uploaded files
+func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, importer.MaxFileSize)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Failed to read the uploaded file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, "Failed to read the uploaded file", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.Service.ImportFile(user.ID, r.FormValue("name"), header.Filename, data); err != nil {
+			serviceError(w, err, "Failed to import file")
+			return
+		}
+
+		http.Redirect(w, r, "/import", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	collections, err := h.Service.Collections(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load collections", http.StatusInternalServerError)
+		return
+	}
+
+	templates.Base(user, templates.Import(collections)).Render(context.Background(), w)
+}
+
+// HandleContinueCollection starts the next passage of an imported collection
+func (h *Handler) HandleContinueCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	collectionID, err := strconv.ParseInt(r.FormValue("collection_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	text, err := h.Service.NextPassage(currentUser(r).ID, collectionID)
+	if err != nil {
+		serviceError(w, err, "Failed to load passage")
+		return
+	}
+
+	// Render the typing exercise template
+	templates.TypingExercise(text).Render(context.Background(), w)
+}
@@ -1,21 +1,31 @@
 package handlers
 
 import (
-	"database/sql"
+	"fmt"
+	"net/http"
 
-	"github.com/janislaus/figure10/internal/llm"
+	"github.com/janislaus/figure10/internal/service"
 )
 
 // Handler holds dependencies for the HTTP handlers
 type Handler struct {
-	DB        *sql.DB
-	Generator *llm.TextGenerator
+	Service *service.Service
 }
 
 // NewHandler creates a new Handler with the given dependencies
-func NewHandler(db *sql.DB, generator *llm.TextGenerator) *Handler {
+func NewHandler(svc *service.Service) *Handler {
 	return &Handler{
-		DB:        db,
-		Generator: generator,
+		Service: svc,
 	}
 }
+
+// serviceError reports a failed service call. Errors caused by the request
+// are shown as they are, internal ones are logged and replaced by message.
+func serviceError(w http.ResponseWriter, err error, message string) {
+	if e, ok := service.AsError(err); ok {
+		http.Error(w, e.Message, service.StatusCode(err))
+		return
+	}
+	fmt.Printf("%s: %v\n", message, err)
+	http.Error(w, message, http.StatusInternalServerError)
+}
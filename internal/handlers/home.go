@@ -3,8 +3,12 @@ package handlers
 import (
 	"context"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/layouts"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
 	"github.com/janislaus/figure10/web/templates"
 )
 
@@ -15,28 +19,128 @@ func (h *Handler) HandleHome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user := currentUser(r)
+
+	// Imported collections that still have passages to type
+	collections, err := h.Service.Collections(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load collections", http.StatusInternalServerError)
+		return
+	}
+	var reading []models.Collection
+	for _, c := range collections {
+		if c.Completed < c.Passages {
+			reading = append(reading, c)
+		}
+	}
+
+	// Type a text from the library again
+	var again *models.Text
+	if textID, err := strconv.ParseInt(r.URL.Query().Get("text"), 10, 64); err == nil {
+		text, err := h.Service.GetText(user.ID, textID)
+		if err != nil {
+			serviceError(w, err, "Failed to load text")
+			return
+		}
+		again = &text
+	}
+
+	// The lessons of the curriculum the user can take
+	lessons, err := h.Service.Curriculum(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load lessons", http.StatusInternalServerError)
+		return
+	}
+	lesson := r.URL.Query().Get("lesson")
+	if lesson == "" {
+		lesson = service.NextLesson(lessons).ID
+	}
+
+	// The on-screen keyboard shows the user's layout, if the user wants it
+	settings, err := h.Service.Settings(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+	var keyboard *layouts.Layout
+	if settings.ShowKeyboard {
+		if keyboard, err = h.Service.Layout(user.ID); err != nil {
+			http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Render the home template
-	templates.Base(templates.Home()).Render(context.Background(), w)
+	templates.Base(user, templates.Home(h.Service.CodeLanguages(), reading, r.URL.Query().Get("collection"), lessons, lesson, again, keyboard)).Render(context.Background(), w)
 }
 
 // HandleHistory renders the history page
 func (h *Handler) HandleHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
+	user := currentUser(r)
 
 	// Get recent sessions
-	sessions, err := db.GetRecentSessions(h.DB, 10)
+	sessions, err := h.Service.ListSessions(user.ID, 10)
 	if err != nil {
 		http.Error(w, "Failed to load history", http.StatusInternalServerError)
 		return
 	}
 
 	// Get common errors
-	errors, err := db.GetCommonErrors(h.DB, 10)
+	errors, err := h.Service.CommonErrors(user.ID, 10)
 	if err != nil {
 		http.Error(w, "Failed to load common errors", http.StatusInternalServerError)
 		return
 	}
 
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "p90"
+	}
+
+	// Get the latency analytics from the keystroke logs
+	report, err := h.Service.LatencyReport(user.ID, sortBy)
+	if err != nil {
+		http.Error(w, "Failed to load keystrokes", http.StatusInternalServerError)
+		return
+	}
+
+	// Get the personal bests
+	bests, err := h.Service.PersonalBests(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load personal bests", http.StatusInternalServerError)
+		return
+	}
+
+	// Get the statistics per test
+	stats, err := h.Service.Stats(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load statistics", http.StatusInternalServerError)
+		return
+	}
+
+	// Get the keyboard layout the heatmap is drawn on
+	layout, err := h.Service.Layout(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	// Get the progress statistics of the selected range
+	query := r.URL.Query()
+	progressQuery := service.ProgressQuery{
+		Period: query.Get("period"),
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Mode:   query.Get("mode"),
+		Test:   query.Get("test"),
+	}
+	progress, err := h.Service.Progress(user.ID, progressQuery, time.Now())
+	if err != nil {
+		serviceError(w, err, "Failed to load progress")
+		return
+	}
+
 	// Render the history template
-	templates.Base(templates.History(sessions, errors)).Render(ctx, w)
+	templates.Base(user, templates.History(sessions, errors, report, sortBy, bests, stats, layout, progress, progressQuery)).Render(ctx, w)
 }
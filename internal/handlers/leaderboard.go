@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/web/templates"
+)
+
+// HandleLeaderboard renders the weekly challenge and, given a text_id, the
+// leaderboard of that text
+func (h *Handler) HandleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	challenge, err := h.Service.WeeklyChallenge(time.Now())
+	if err != nil {
+		http.Error(w, "Failed to load the weekly challenge", http.StatusInternalServerError)
+		return
+	}
+
+	// The leaderboard of a single text is optional
+	var text *models.Text
+	var board []models.LeaderboardEntry
+	if textIDStr := r.URL.Query().Get("text_id"); textIDStr != "" {
+		textID, err := strconv.ParseInt(textIDStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid text ID", http.StatusBadRequest)
+			return
+		}
+
+		loaded, err := h.Service.GetText(user.ID, textID)
+		if err != nil {
+			serviceError(w, err, "Failed to load text")
+			return
+		}
+		board, err = h.Service.TextLeaderboard(user.ID, textID)
+		if err != nil {
+			serviceError(w, err, "Failed to load leaderboard")
+			return
+		}
+		text = &loaded
+	}
+
+	templates.Base(user, templates.Leaderboards(user, challenge, text, board, h.Service.MinAccuracy)).Render(context.Background(), w)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/janislaus/figure10/web/templates"
+)
+
+// HandleLessons renders the curriculum of the user's layout with the user's
+// progress
+func (h *Handler) HandleLessons(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	lessons, err := h.Service.Curriculum(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load lessons", http.StatusInternalServerError)
+		return
+	}
+
+	layout, err := h.Service.Layout(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	templates.Base(user, templates.Lessons(lessons, layout.Name)).Render(context.Background(), w)
+}
+
+// HandleGenerateLesson generates a text for a lesson of the curriculum, the
+// next lesson unless one is selected
+func (h *Handler) HandleGenerateLesson(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Use the LLM unless the offline word list was requested
+	text, err := h.Service.GenerateLesson(r.Context(), currentUser(r).ID, r.FormValue("lesson"), r.FormValue("offline") != "")
+	if err != nil {
+		serviceError(w, err, "Failed to generate lesson")
+		return
+	}
+
+	// Render the typing exercise template
+	templates.TypingExercise(text).Render(context.Background(), w)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/janislaus/figure10/internal/service"
+	"github.com/janislaus/figure10/web/templates"
+)
+
+// libraryPageSize is the number of texts shown per library page
+const libraryPageSize = 20
+
+// HandleLibrary renders the library with the texts matching the filters
+func (h *Handler) HandleLibrary(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	user := currentUser(r)
+
+	query := libraryQuery(r.URL.Query())
+	texts, err := h.Service.Library(user.ID, query)
+	if err != nil {
+		serviceError(w, err, "Failed to load library")
+		return
+	}
+
+	tags, err := h.Service.Tags(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load tags", http.StatusInternalServerError)
+		return
+	}
+
+	collections, err := h.Service.Collections(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load collections", http.StatusInternalServerError)
+		return
+	}
+
+	templates.Base(user, templates.Library(texts, query, tags, collections)).Render(ctx, w)
+}
+
+// libraryQuery reads the library filters from the URL query
+func libraryQuery(values url.Values) service.LibraryQuery {
+	query := service.LibraryQuery{
+		Query:      values.Get("q"),
+		Length:     values.Get("length"),
+		Difficulty: values.Get("difficulty"),
+		Source:     values.Get("source"),
+		Tag:        values.Get("tag"),
+		Limit:      libraryPageSize,
+	}
+	if id, err := strconv.ParseInt(values.Get("collection"), 10, 64); err == nil {
+		query.CollectionID = id
+	}
+	if offset, err := strconv.Atoi(values.Get("offset")); err == nil && offset > 0 {
+		query.Offset = offset
+	}
+	return query
+}
+
+// HandleTagText saves the tags of a text and returns to the library
+func (h *Handler) HandleTagText(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	textID, err := strconv.ParseInt(r.FormValue("text_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid text ID", http.StatusBadRequest)
+		return
+	}
+
+	// Tags are entered as a comma separated list
+	_, err = h.Service.TagText(currentUser(r).ID, textID, []string{r.FormValue("tags")})
+	if err != nil {
+		serviceError(w, err, "Failed to save tags")
+		return
+	}
+
+	// Go back to the same filters
+	http.Redirect(w, r, "/library?"+r.FormValue("filters"), http.StatusSeeOther)
+}
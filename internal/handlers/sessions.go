@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/janislaus/figure10/web/templates"
+)
+
+// HandleReplay renders the playback of a completed session from its
+// recorded keystrokes
+func (h *Handler) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	sessionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	replay, err := h.Service.Replay(user.ID, sessionID)
+	if err != nil {
+		serviceError(w, err, "Failed to load session")
+		return
+	}
+
+	templates.Base(user, templates.Replay(replay)).Render(context.Background(), w)
+}
+
+// HandleSession renders the breakdown of a session word by word
+func (h *Handler) HandleSession(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	sessionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	breakdown, err := h.Service.Breakdown(user.ID, sessionID)
+	if err != nil {
+		serviceError(w, err, "Failed to load session")
+		return
+	}
+
+	templates.Base(user, templates.SessionDetail(breakdown)).Render(context.Background(), w)
+}
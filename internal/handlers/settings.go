@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/web/templates"
+)
+
+// HandleSettings renders and saves the user's settings
+func (h *Handler) HandleSettings(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	if r.Method == http.MethodPost {
+		maxLength, err := strconv.Atoi(r.FormValue("max_length"))
+		if err != nil || maxLength < 0 {
+			maxLength = 0
+		}
+
+		settings := models.UserSettings{
+			UserID:           user.ID,
+			StripMarkdown:    r.FormValue("strip_markdown") != "",
+			ASCIIPunctuation: r.FormValue("ascii_punctuation") != "",
+			MaxLength:        maxLength,
+			AllowedChars:     r.FormValue("allowed_chars"),
+			Layout:           r.FormValue("layout"),
+			ShowKeyboard:     r.FormValue("show_keyboard") != "",
+		}
+		if err := h.Service.SaveSettings(settings); err != nil {
+			serviceError(w, err, "Failed to save settings")
+			return
+		}
+
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	settings, err := h.Service.Settings(user.ID)
+	if err != nil {
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return
+	}
+
+	templates.Base(user, templates.Settings(settings)).Render(context.Background(), w)
+}
@@ -6,11 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/janislaus/figure10/internal/db"
 	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/service"
+	"github.com/janislaus/figure10/internal/textgen"
 	"github.com/janislaus/figure10/web/templates"
 )
 
@@ -21,36 +21,105 @@ func (h *Handler) HandleGenerateText(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the prompt from the form
-	prompt := r.FormValue("prompt")
-	if prompt == "" {
-		prompt = "Give me a general typing practice text"
+	// Generic prompts are served from the text pool, other LLM texts are
+	// streamed into the exercise while they are generated
+	var text models.Text
+	var err error
+	if r.FormValue("source") == "offline" {
+		text, err = h.Service.GenerateOffline(offlineOptions(r))
+	} else {
+		prompt, difficulty := r.FormValue("prompt"), r.FormValue("difficulty")
+		var pooled bool
+		text, pooled, err = h.Service.PooledText(currentUser(r).ID, prompt, difficulty)
+		if err == nil && !pooled {
+			templates.StreamingExercise(prompt, difficulty).Render(context.Background(), w)
+			return
+		}
 	}
-
-	// Generate text using the LLM
-	content, err := h.Generator.GenerateText(prompt)
 	if err != nil {
-		http.Error(w, "Failed to generate text", http.StatusInternalServerError)
+		serviceError(w, err, "Failed to generate text")
 		return
 	}
 
-	// Save the text to the database
-	textID, err := db.SaveText(h.DB, content, prompt)
+	// Render the typing exercise template
+	templates.TypingExercise(text).Render(context.Background(), w)
+}
+
+// HandleGenerateStream generates a text with the LLM and streams it as
+// server-sent events: a "chunk" event with every new sentence, then a "done"
+// event with the stored text, or a "failed" event with an error message
+func (h *Handler) HandleGenerateStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	send := func(event string, data interface{}) error {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	text, err := h.Service.GenerateTextStream(r.Context(), currentUser(r).ID, r.FormValue("prompt"), r.FormValue("difficulty"), func(sentences string) error {
+		return send("chunk", map[string]string{"content": sentences})
+	})
 	if err != nil {
-		http.Error(w, "Failed to save text", http.StatusInternalServerError)
+		message := "Failed to generate text"
+		if e, ok := service.AsError(err); ok {
+			message = e.Message
+		} else {
+			fmt.Printf("%s: %v\n", message, err)
+		}
+		send("failed", map[string]string{"error": message})
 		return
 	}
 
-	// Create a Text model
-	text := models.Text{
-		ID:        textID,
-		Content:   content,
-		Prompt:    prompt,
-		CreatedAt: time.Now(),
+	send("done", map[string]interface{}{
+		"text_id": text.ID,
+		"content": text.Content,
+		"prompt":  text.Prompt,
+	})
+}
+
+// offlineOptions reads the offline generator knobs from the form. A missing
+// seed picks a new random one.
+func offlineOptions(r *http.Request) textgen.Options {
+	opts := textgen.DefaultOptions(time.Now().UnixNano())
+
+	if words, err := strconv.Atoi(r.FormValue("words")); err == nil && words > 0 && words <= 1000 {
+		opts.Words = words
+	}
+	if rank, err := strconv.Atoi(r.FormValue("rank")); err == nil {
+		for _, allowed := range textgen.VocabularyRanks {
+			if rank == allowed {
+				opts.Rank = rank
+			}
+		}
+	}
+	if punctuation, err := strconv.ParseFloat(r.FormValue("punctuation"), 64); err == nil && punctuation >= 0 && punctuation <= 100 {
+		opts.Punctuation = punctuation / 100
+	}
+	opts.Capitalize = r.FormValue("capitalize") != ""
+	opts.Numbers = r.FormValue("numbers") != ""
+	if seed, err := strconv.ParseInt(r.FormValue("seed"), 10, 64); err == nil {
+		opts.Seed = seed
 	}
 
-	// Render the typing exercise template
-	templates.TypingExercise(text).Render(context.Background(), w)
+	return opts
 }
 
 // HandleStartSession starts a new typing session
@@ -68,93 +137,139 @@ func (h *Handler) HandleStartSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the text from the database
-	text, err := db.GetTextByID(h.DB, textID)
+	// The test the exercise was started for, the full text by default
+	test, err := service.ParseTest(r.FormValue("test"))
 	if err != nil {
-		http.Error(w, "Failed to get text", http.StatusInternalServerError)
+		serviceError(w, err, "Invalid test")
 		return
 	}
 
-	// Return the text content as JSON
+	// Create the session so keystrokes can be recorded against it
+	session, text, err := h.Service.StartSession(currentUser(r).ID, textID, test)
+	if err != nil {
+		serviceError(w, err, "Failed to start session")
+		return
+	}
+
+	// Return the session and text content as JSON
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"text_id": text.ID,
-		"content": text.Content,
-		"prompt":  text.Prompt,
+		"session_id": session.ID,
+		"text_id":    text.ID,
+		"content":    text.Content,
+		"prompt":     text.Prompt,
 	})
 }
 
-// HandleCheckTyping checks the current typing progress
-func (h *Handler) HandleCheckTyping(w http.ResponseWriter, r *http.Request) {
+// HandleStartTest generates the text of a test and renders the exercise
+func (h *Handler) HandleStartTest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Get the text ID, current input, and start time from the form
-	textIDStr := r.FormValue("text_id")
-	textID, err := strconv.ParseInt(textIDStr, 10, 64)
+	test, err := service.ParseTest(r.FormValue("test"))
 	if err != nil {
-		http.Error(w, "Invalid text ID", http.StatusBadRequest)
+		serviceError(w, err, "Invalid test")
 		return
 	}
 
-	currentInput := r.FormValue("current_input")
-	startTimeStr := r.FormValue("start_time")
-	startTime, err := strconv.ParseInt(startTimeStr, 10, 64)
+	text, err := h.Service.StartTest(test)
 	if err != nil {
-		http.Error(w, "Invalid start time", http.StatusBadRequest)
+		serviceError(w, err, "Failed to generate text")
 		return
 	}
 
-	// Get the text from the database
-	text, err := db.GetTextByID(h.DB, textID)
+	// Render the typing exercise template for the test
+	templates.TestExercise(text, test).Render(context.Background(), w)
+}
+
+// HandleExtendText appends the next chunk of a streamed test to its text
+func (h *Handler) HandleExtendText(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(r.FormValue("session_id"), 10, 64)
 	if err != nil {
-		http.Error(w, "Failed to get text", http.StatusInternalServerError)
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
 		return
 	}
 
-	// Calculate metrics
-	elapsedTime := float64(time.Now().UnixMilli()-startTime) / 1000.0 // in seconds
-	totalChars := len(text.Content)
-	currentPos := len(currentInput)
-
-	// Count errors
-	errorCount := 0
-	for i := 0; i < currentPos && i < totalChars; i++ {
-		if i >= len(currentInput) || text.Content[i] != currentInput[i] {
-			errorCount++
-		}
+	more, err := h.Service.ExtendText(currentUser(r).ID, sessionID)
+	if err != nil {
+		serviceError(w, err, "Failed to extend text")
+		return
+	}
+
+	// Return the appended content as JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"content": more})
+}
+
+// HandleRecordKeystrokes appends a batch of raw keystroke events to a session
+func (h *Handler) HandleRecordKeystrokes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// Calculate WPM (assuming 5 chars per word)
-	var wpm float64
-	if elapsedTime > 0 {
-		wpm = float64(currentPos) / 5.0 / (elapsedTime / 60.0)
+	// Parse the request body
+	var request struct {
+		SessionID int64              `json:"session_id"`
+		Events    []models.Keystroke `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	// Calculate accuracy
-	var accuracy float64
-	if currentPos > 0 {
-		accuracy = 100.0 * float64(currentPos-errorCount) / float64(currentPos)
+	// Only sessions in progress accept keystrokes
+	err := h.Service.RecordKeystrokes(currentUser(r).ID, request.SessionID, request.Events)
+	if err != nil {
+		serviceError(w, err, "Failed to save keystrokes")
+		return
 	}
 
-	// Check if the current character is correct
-	correct := true
-	if currentPos < totalChars && currentPos > 0 {
-		if currentInput[currentPos-1] != text.Content[currentPos-1] {
-			correct = false
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"recorded": len(request.Events),
+	})
+}
+
+// HandleCheckTyping checks the current typing progress
+func (h *Handler) HandleCheckTyping(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := currentUser(r)
+
+	// Get the text ID, current input, and start time from the form
+	textIDStr := r.FormValue("text_id")
+	textID, err := strconv.ParseInt(textIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid text ID", http.StatusBadRequest)
+		return
+	}
+
+	currentInput := r.FormValue("current_input")
+	startTimeStr := r.FormValue("start_time")
+	startTime, err := strconv.ParseInt(startTimeStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid start time", http.StatusBadRequest)
+		return
 	}
 
-	// Create the response
-	check := models.TypingCheck{
-		Correct:    correct,
-		CurrentWPM: wpm,
-		CurrentAcc: accuracy,
-		CurrentPos: currentPos,
-		TotalChars: totalChars,
-		ErrorCount: errorCount,
+	// Calculate metrics
+	elapsed := time.Duration(time.Now().UnixMilli()-startTime) * time.Millisecond
+	check, err := h.Service.CheckTyping(user.ID, textID, currentInput, elapsed)
+	if err != nil {
+		serviceError(w, err, "Failed to get text")
+		return
 	}
 
 	// Return the check result as JSON
@@ -169,35 +284,30 @@ func (h *Handler) HandleSubmitResult(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse the request body
-	var result models.TypingResult
-	err := json.NewDecoder(r.Body).Decode(&result)
+	// Parse the request body. Only the session is taken from the client,
+	// the result is recomputed from the recorded keystrokes.
+	var request struct {
+		SessionID int64 `json:"session_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	// Save the session to the database
-	sessionID, err := db.SaveSession(h.DB, result.TextID, result.WPM, result.Accuracy, result.Errors)
+	// Score the session from the keystroke log and save the results
+	result, err := h.Service.SubmitSession(currentUser(r).ID, request.SessionID)
 	if err != nil {
-		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+		serviceError(w, err, "Failed to save session")
 		return
 	}
 
-	// Save the error details
-	for _, e := range result.ErrorDetails {
-		err := db.SaveTypingError(h.DB, sessionID, e.ExpectedChar, e.TypedChar, e.Position)
-		if err != nil {
-			// Log the error but continue
-			fmt.Printf("Failed to save typing error: %v\n", err)
-		}
-	}
-
-	// Return success
+	// Return the recomputed result
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success":    true,
-		"session_id": sessionID,
+		"session_id": result.SessionID,
+		"result":     result,
 	})
 }
 
@@ -219,46 +329,46 @@ func (h *Handler) HandleGeneratePractice(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if len(request.Words) == 0 {
-		http.Error(w, "No words provided", http.StatusBadRequest)
+	text, err := h.Service.GeneratePractice(r.Context(), currentUser(r).ID, request.Words)
+	if err != nil {
+		serviceError(w, err, "Failed to generate practice text")
 		return
 	}
 
-	// Create a more specific prompt that ensures each word appears multiple times
-	prompt := fmt.Sprintf(
-		"Create a typing practice paragraph that includes EACH of these words AT LEAST 10 TIMES: %s. "+
-			"Make sure each word appears multiple times throughout the text. "+
-			"The text should be coherent but focus on repeating these words frequently for practice.",
-		strings.Join(request.Words, ", "))
+	// Render the typing exercise template
+	templates.TypingExercise(text).Render(context.Background(), w)
+}
 
-	fmt.Printf("Generating practice with prompt: %s\n", prompt)
+// HandleGenerateAdaptive generates a practice text weighted toward the
+// user's most error-prone letters and slowest bigrams
+func (h *Handler) HandleGenerateAdaptive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	// Generate text using the LLM
-	content, err := h.Generator.GenerateText(prompt)
+	// Use the LLM unless the offline word list was requested
+	text, err := h.Service.GenerateAdaptive(r.Context(), currentUser(r).ID, r.FormValue("offline") != "")
 	if err != nil {
-		http.Error(w, "Failed to generate practice text", http.StatusInternalServerError)
+		serviceError(w, err, "Failed to generate drill")
 		return
 	}
 
-	// Verify that each word appears multiple times
-	for _, word := range request.Words {
-		count := strings.Count(strings.ToLower(content), strings.ToLower(word))
-		fmt.Printf("Word '%s' appears %d times in generated text\n", word, count)
-	}
+	// Render the typing exercise template
+	templates.TypingExercise(text).Render(context.Background(), w)
+}
 
-	// Save the text to the database
-	textID, err := db.SaveText(h.DB, content, "Practice: "+strings.Join(request.Words, ", "))
-	if err != nil {
-		http.Error(w, "Failed to save text", http.StatusInternalServerError)
+// HandleGenerateCode picks a code snippet of the selected language
+func (h *Handler) HandleGenerateCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Create a Text model
-	text := models.Text{
-		ID:        textID,
-		Content:   content,
-		Prompt:    prompt,
-		CreatedAt: time.Now(),
+	text, err := h.Service.GenerateCode(r.FormValue("language"), time.Now().UnixNano())
+	if err != nil {
+		serviceError(w, err, "Failed to load code")
+		return
 	}
 
 	// Render the typing exercise template
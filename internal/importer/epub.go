@@ -0,0 +1,217 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// MaxBookSize bounds the uncompressed size of the files read from an EPUB
+// archive, all together, so a small archive can't expand to fill the memory
+const MaxBookSize = 4 * MaxFileSize
+
+// book is an EPUB archive being read
+type book struct {
+	archive   *zip.Reader
+	remaining int64 // uncompressed bytes left to read
+}
+
+// container is META-INF/container.xml, which points to the package file
+type container struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// packageFile is the OPF package file listing the book's content in
+// reading order
+type packageFile struct {
+	Title    string `xml:"metadata>title"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// parseEPUB reads the chapters of an EPUB book in reading order
+func parseEPUB(data []byte) (Document, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Document{}, fmt.Errorf("not a valid EPUB file: %v", err)
+	}
+	b := &book{archive: archive, remaining: MaxBookSize}
+
+	var c container
+	if err := b.readXML("META-INF/container.xml", &c); err != nil {
+		return Document{}, err
+	}
+	if len(c.Rootfiles) == 0 {
+		return Document{}, fmt.Errorf("EPUB file has no package document")
+	}
+	opfPath := c.Rootfiles[0].FullPath
+
+	var pkg packageFile
+	if err := b.readXML(opfPath, &pkg); err != nil {
+		return Document{}, err
+	}
+
+	hrefs := map[string]string{}
+	for _, item := range pkg.Manifest {
+		if strings.Contains(item.MediaType, "html") {
+			hrefs[item.ID] = item.Href
+		}
+	}
+
+	doc := Document{Title: strings.TrimSpace(pkg.Title)}
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+
+		file, err := b.openFile(path.Join(path.Dir(opfPath), href))
+		if err != nil {
+			return Document{}, err
+		}
+		chapter, err := htmlParagraphs(file)
+		file.Close()
+		if err != nil {
+			return Document{}, fmt.Errorf("reading %s: %v", href, err)
+		}
+		doc.Paragraphs = append(doc.Paragraphs, chapter...)
+	}
+
+	return doc, nil
+}
+
+// readXML decodes an XML file of the archive
+func (b *book) readXML(name string, v interface{}) error {
+	file, err := b.openFile(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := xml.NewDecoder(file).Decode(v); err != nil {
+		return fmt.Errorf("reading %s: %v", name, err)
+	}
+	return nil
+}
+
+// openFile opens a file of the archive. Reading fails once the book grows
+// beyond MaxBookSize, whatever sizes the archive claims.
+func (b *book) openFile(name string) (io.ReadCloser, error) {
+	for _, f := range b.archive.File {
+		if f.Name != name {
+			continue
+		}
+		if f.UncompressedSize64 > uint64(b.remaining) {
+			return nil, errBookTooLarge
+		}
+		file, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		return &limitedFile{ReadCloser: file, book: b}, nil
+	}
+	return nil, fmt.Errorf("EPUB file is missing %s", name)
+}
+
+// errBookTooLarge is returned for books beyond MaxBookSize
+var errBookTooLarge = fmt.Errorf("EPUB file is larger than %d MB uncompressed", MaxBookSize>>20)
+
+// limitedFile is a file of an archive counting its bytes against the book
+type limitedFile struct {
+	io.ReadCloser
+	book *book
+}
+
+func (f *limitedFile) Read(p []byte) (int, error) {
+	if f.book.remaining <= 0 {
+		return 0, errBookTooLarge
+	}
+	if int64(len(p)) > f.book.remaining {
+		p = p[:f.book.remaining]
+	}
+	n, err := f.ReadCloser.Read(p)
+	f.book.remaining -= int64(n)
+	return n, err
+}
+
+// blockElements end a paragraph of text
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"tr": true, "section": true, "article": true, "pre": true, "hr": true,
+}
+
+// skippedElements hold no readable text
+var skippedElements = map[string]bool{
+	"head": true, "script": true, "style": true,
+}
+
+// htmlParagraphs extracts the paragraphs of an (X)HTML document
+func htmlParagraphs(r io.Reader) ([]string, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var result []string
+	var current strings.Builder
+	flush := func() {
+		if p := strings.Join(strings.Fields(current.String()), " "); p != "" {
+			result = append(result, p)
+		}
+		current.Reset()
+	}
+
+	skipping := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if skippedElements[name] {
+				skipping++
+			}
+			if blockElements[name] {
+				flush()
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			if skippedElements[name] && skipping > 0 {
+				skipping--
+			}
+			if blockElements[name] {
+				flush()
+			}
+		case xml.CharData:
+			if skipping == 0 {
+				current.Write(t)
+			}
+		}
+	}
+	flush()
+
+	return result, nil
+}
@@ -0,0 +1,178 @@
+// Package importer turns text files, Markdown, EPUB books and SRT subtitles
+// into typing passages
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/janislaus/figure10/internal/normalize"
+)
+
+// Supported formats
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatEPUB     = "epub"
+	FormatSRT      = "srt"
+)
+
+// DefaultPassageLength is the length passages are filled up to, in characters
+const DefaultPassageLength = 400
+
+// MaxFileSize is the largest file accepted for import, in bytes
+const MaxFileSize = 20 << 20
+
+// Document is the plain text content of an imported file
+type Document struct {
+	Title      string
+	Format     string
+	Paragraphs []string
+}
+
+// FormatOf returns the format of a file by its extension, or "" if it isn't
+// supported
+func FormatOf(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", ".text":
+		return FormatText
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".epub":
+		return FormatEPUB
+	case ".srt":
+		return FormatSRT
+	}
+	return ""
+}
+
+// Parse extracts the text of a file. The format is taken from the file name.
+func Parse(filename string, data []byte) (Document, error) {
+	format := FormatOf(filename)
+	if format != FormatEPUB && !utf8.Valid(data) {
+		return Document{}, fmt.Errorf("%s is not valid UTF-8 text", filepath.Base(filename))
+	}
+
+	var doc Document
+	var err error
+	switch format {
+	case FormatText:
+		doc = parseText(string(data))
+	case FormatMarkdown:
+		doc = parseMarkdown(string(data))
+	case FormatEPUB:
+		doc, err = parseEPUB(data)
+	case FormatSRT:
+		doc = parseSRT(string(data))
+	default:
+		return Document{}, fmt.Errorf("unsupported file type %q, use .txt, .md, .epub or .srt", filepath.Ext(filename))
+	}
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc.Format = format
+	if doc.Title == "" {
+		doc.Title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	if len(doc.Paragraphs) == 0 {
+		return Document{}, fmt.Errorf("%s contains no text", filepath.Base(filename))
+	}
+	return doc, nil
+}
+
+var (
+	gutenbergStart = regexp.MustCompile(`(?m)^\*\*\* ?START OF (THE|THIS) PROJECT GUTENBERG.*$`)
+	gutenbergEnd   = regexp.MustCompile(`(?m)^\*\*\* ?END OF (THE|THIS) PROJECT GUTENBERG.*$`)
+	gutenbergTitle = regexp.MustCompile(`(?m)^Title:\s*(.+)$`)
+	blankLines     = regexp.MustCompile(`\n\s*\n`)
+)
+
+// parseText reads plain text. Paragraphs are separated by blank lines, and
+// the license header and footer of Project Gutenberg books are dropped.
+func parseText(text string) Document {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var doc Document
+	if m := gutenbergTitle.FindStringSubmatch(text); m != nil {
+		doc.Title = strings.TrimSpace(m[1])
+	}
+	if loc := gutenbergStart.FindStringIndex(text); loc != nil {
+		text = text[loc[1]:]
+	}
+	if loc := gutenbergEnd.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+
+	doc.Paragraphs = paragraphs(text)
+	return doc
+}
+
+var (
+	fencedCode    = regexp.MustCompile("(?ms)^[ \\t]*```.*?^[ \\t]*```[ \\t]*$")
+	markdownTitle = regexp.MustCompile(`(?m)^#\s+(.+)$`)
+)
+
+// parseMarkdown reads Markdown. Code blocks are dropped and the markup of
+// the remaining text is removed.
+func parseMarkdown(text string) Document {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = fencedCode.ReplaceAllString(text, "")
+
+	var doc Document
+	if m := markdownTitle.FindStringSubmatch(text); m != nil {
+		doc.Title = strings.TrimSpace(m[1])
+	}
+
+	text = normalize.Normalize(text, normalize.Options{StripMarkdown: true}).Text
+	doc.Paragraphs = paragraphs(text)
+	return doc
+}
+
+var (
+	srtTiming = regexp.MustCompile(`^\d\d:\d\d:\d\d[,.]\d+\s*-->`)
+	srtIndex  = regexp.MustCompile(`^\d+$`)
+	srtTags   = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+)
+
+// parseSRT reads subtitles. Cue numbers and timings are dropped and the
+// dialogue becomes running text.
+func parseSRT(text string) Document {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var cues []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || srtIndex.MatchString(line) || srtTiming.MatchString(line) {
+			continue
+		}
+		line = srtTags.ReplaceAllString(line, "")
+		line = strings.TrimSpace(strings.TrimPrefix(line, "- "))
+		if line != "" {
+			cues = append(cues, line)
+		}
+	}
+
+	var doc Document
+	if len(cues) > 0 {
+		doc.Paragraphs = []string{strings.Join(cues, " ")}
+	}
+	return doc
+}
+
+// paragraphs splits text at blank lines and joins the lines of each
+// paragraph, which undoes hard wrapping
+func paragraphs(text string) []string {
+	var result []string
+	for _, p := range blankLines.Split(text, -1) {
+		p = strings.Join(strings.Fields(p), " ")
+		if p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// abbreviations end with a period without ending the sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "st": true, "jr": true,
+	"sr": true, "prof": true, "vs": true, "etc": true, "e.g": true, "i.e": true,
+	"no": true, "vol": true, "ch": true, "fig": true, "approx": true,
+}
+
+// Sentences splits a paragraph into sentences. A sentence ends at ".", "!"
+// or "?", followed by any closing quotes or brackets and a space, unless the
+// period belongs to an abbreviation or an initial.
+func Sentences(paragraph string) []string {
+	var result []string
+	start := 0
+	for i, r := range paragraph {
+		if i < start || (r != '.' && r != '!' && r != '?') {
+			continue
+		}
+
+		// Include closing punctuation such as quotes
+		end := i + 1
+		for end < len(paragraph) {
+			next, size := utf8.DecodeRuneInString(paragraph[end:])
+			if !strings.ContainsRune(`"')].!?`, next) {
+				break
+			}
+			end += size
+		}
+		if end < len(paragraph) && paragraph[end] != ' ' {
+			continue
+		}
+		if r == '.' && isAbbreviation(paragraph[start:i]) {
+			continue
+		}
+
+		if sentence := strings.TrimSpace(paragraph[start:end]); sentence != "" {
+			result = append(result, sentence)
+		}
+		start = end
+	}
+
+	if rest := strings.TrimSpace(paragraph[start:]); rest != "" {
+		result = append(result, rest)
+	}
+	return result
+}
+
+// isAbbreviation reports whether the text ends with a word that is usually
+// followed by a period inside a sentence
+func isAbbreviation(text string) bool {
+	word := text
+	if i := strings.LastIndexAny(text, " (\"'"); i >= 0 {
+		word = text[i+1:]
+	}
+	if utf8.RuneCountInString(word) == 1 {
+		r, _ := utf8.DecodeRuneInString(word)
+		return unicode.IsUpper(r)
+	}
+	return abbreviations[strings.ToLower(word)]
+}
+
+// Split groups the sentences of the paragraphs into passages of about
+// length characters. Sentences are never cut, so a passage holding a single
+// long sentence can be longer.
+func Split(paragraphs []string, length int) []string {
+	if length <= 0 {
+		length = DefaultPassageLength
+	}
+
+	var passages []string
+	var current []string
+	size := 0
+	for _, p := range paragraphs {
+		for _, sentence := range Sentences(p) {
+			n := utf8.RuneCountInString(sentence)
+			if size > 0 && size+1+n > length {
+				passages = append(passages, strings.Join(current, " "))
+				current, size = nil, 0
+			}
+			if size > 0 {
+				size++
+			}
+			current = append(current, sentence)
+			size += n
+		}
+	}
+	if len(current) > 0 {
+		passages = append(passages, strings.Join(current, " "))
+	}
+	return passages
+}
@@ -0,0 +1,270 @@
+// Package layouts knows which key, finger, hand and row type each character
+// on common keyboard layouts. The layouts are defined in embedded text files
+// listing the keys of each row.
+package layouts
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Fingers, ordered from the left pinky to the right pinky
+const (
+	LeftPinky   = "left-pinky"
+	LeftRing    = "left-ring"
+	LeftMiddle  = "left-middle"
+	LeftIndex   = "left-index"
+	Thumb       = "thumb"
+	RightIndex  = "right-index"
+	RightMiddle = "right-middle"
+	RightRing   = "right-ring"
+	RightPinky  = "right-pinky"
+)
+
+// Fingers lists every finger from left to right
+var Fingers = []string{LeftPinky, LeftRing, LeftMiddle, LeftIndex, Thumb, RightIndex, RightMiddle, RightRing, RightPinky}
+
+// Hands
+const (
+	Left  = "left"
+	Right = "right"
+)
+
+// Hands lists both hands
+var Hands = []string{Left, Right}
+
+// Rows, from top to bottom
+const (
+	RowNumber = "number"
+	RowTop    = "top"
+	RowHome   = "home"
+	RowBottom = "bottom"
+	RowSpace  = "space"
+)
+
+// Rows lists every row from top to bottom
+var Rows = []string{RowNumber, RowTop, RowHome, RowBottom, RowSpace}
+
+// Default is the layout of users who didn't choose one
+const Default = "qwerty"
+
+// rowFingers assigns the keys of each row to fingers by their column. Keys
+// past the end of a row are typed with the right pinky.
+var rowFingers = map[string][]string{
+	RowNumber: {LeftPinky, LeftPinky, LeftRing, LeftMiddle, LeftIndex, LeftIndex, RightIndex, RightIndex, RightMiddle, RightRing, RightPinky},
+	RowTop:    {LeftPinky, LeftRing, LeftMiddle, LeftIndex, LeftIndex, RightIndex, RightIndex, RightMiddle, RightRing, RightPinky},
+	RowHome:   {LeftPinky, LeftRing, LeftMiddle, LeftIndex, LeftIndex, RightIndex, RightIndex, RightMiddle, RightRing, RightPinky},
+	RowBottom: {LeftPinky, LeftRing, LeftMiddle, LeftIndex, LeftIndex, RightIndex, RightIndex, RightMiddle, RightRing, RightPinky},
+}
+
+// isoBottomKeys is the number of keys of a bottom row with the extra key
+// of ISO keyboards, which sits left of the first letter and is typed with
+// the left pinky
+const isoBottomKeys = 11
+
+// Key is the position of a character on a layout
+type Key struct {
+	Row    string `json:"row"`
+	Column int    `json:"column"` // 0 is the leftmost letter key of the row, Tab and the extra ISO key are -1
+	Finger string `json:"finger"`
+	Hand   string `json:"hand"` // empty for the thumbs
+	Shift  bool   `json:"shift"`
+	AltGr  bool   `json:"altgr"`
+}
+
+// Layout maps the characters of a keyboard layout to their keys
+type Layout struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	keys  map[string]Key
+	plain map[string]string // the character each key types without modifiers
+}
+
+// Key returns the key typing a character and whether the layout has one
+func (l *Layout) Key(char string) (Key, bool) {
+	key, ok := l.keys[char]
+	return key, ok
+}
+
+// KeyMap returns the key of every character the layout types
+func (l *Layout) KeyMap() map[string]Key {
+	keys := make(map[string]Key, len(l.keys))
+	for char, key := range l.keys {
+		keys[char] = key
+	}
+	return keys
+}
+
+// Plain returns the character typed without modifiers by the key typing a
+// character, like "1" for "!" on QWERTY, and whether the layout has one
+func (l *Layout) Plain(char string) (string, bool) {
+	plain, ok := l.plain[char]
+	return plain, ok
+}
+
+// Keys returns the character keys of a row in column order, each with the
+// character it types without modifiers. Space, Tab and Enter are left out.
+func (l *Layout) Keys(row string) []string {
+	var chars []string
+	for char, key := range l.keys {
+		if key.Row == row && !key.Shift && !key.AltGr && strings.TrimSpace(char) != "" {
+			chars = append(chars, char)
+		}
+	}
+	sort.Slice(chars, func(i, j int) bool {
+		return l.keys[chars[i]].Column < l.keys[chars[j]].Column
+	})
+	return chars
+}
+
+// Chars returns every character the layout types, in row and column order
+// and plain before Shift before AltGr
+func (l *Layout) Chars() []string {
+	chars := make([]string, 0, len(l.keys))
+	for char := range l.keys {
+		chars = append(chars, char)
+	}
+	level := func(key Key) int {
+		switch {
+		case key.AltGr:
+			return 2
+		case key.Shift:
+			return 1
+		}
+		return 0
+	}
+	sort.Slice(chars, func(i, j int) bool {
+		a, b := l.keys[chars[i]], l.keys[chars[j]]
+		if a.Row != b.Row {
+			return slices.Index(Rows, a.Row) < slices.Index(Rows, b.Row)
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return level(a) < level(b)
+	})
+	return chars
+}
+
+// HandOf returns the hand typing with a finger, or "" for the thumbs
+func HandOf(finger string) string {
+	switch {
+	case strings.HasPrefix(finger, Left+"-"):
+		return Left
+	case strings.HasPrefix(finger, Right+"-"):
+		return Right
+	}
+	return ""
+}
+
+//go:embed defs/*.txt
+var defs embed.FS
+
+// layouts are the parsed definitions by ID
+var layouts = map[string]*Layout{}
+
+func init() {
+	entries, err := defs.ReadDir("defs")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		data, err := defs.ReadFile(path.Join("defs", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		id := strings.TrimSuffix(entry.Name(), ".txt")
+		layout, err := parse(id, string(data))
+		if err != nil {
+			panic(fmt.Sprintf("layout %s: %v", id, err))
+		}
+		layouts[id] = layout
+	}
+}
+
+// parse reads a layout definition. Lines start with "name" followed by the
+// name of the layout, or with a row followed by its keys from left to right.
+// Each key is written as the characters it types plain, with Shift and with
+// AltGr. Lines starting with "#" are comments.
+func parse(id, data string) (*Layout, error) {
+	layout := &Layout{ID: id, keys: map[string]Key{}, plain: map[string]string{}}
+	add := func(char, plain string, key Key) {
+		// The first key typing a character wins, e.g. over a dead key
+		if _, ok := layout.keys[char]; !ok {
+			layout.keys[char] = key
+			layout.plain[char] = plain
+		}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "#" {
+			continue
+		}
+
+		row, keys := fields[0], fields[1:]
+		if row == "name" {
+			layout.Name = strings.Join(keys, " ")
+			continue
+		}
+		fingers, ok := rowFingers[row]
+		if !ok {
+			return nil, fmt.Errorf("unknown row %q", row)
+		}
+		// Columns of the extra ISO key's row are shifted so they line up
+		// with the bottom row of other keyboards
+		offset := 0
+		if row == RowBottom && len(keys) == isoBottomKeys {
+			fingers = append([]string{LeftPinky}, fingers...)
+			offset = -1
+		}
+
+		for i, chars := range keys {
+			finger := RightPinky
+			if i < len(fingers) {
+				finger = fingers[i]
+			}
+			key := Key{Row: row, Column: i + offset, Finger: finger, Hand: HandOf(finger)}
+			runes := []rune(chars)
+			for i, char := range runes {
+				key.Shift = i == 1
+				key.AltGr = i == 2
+				add(string(char), string(runes[0]), key)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if layout.Name == "" {
+		return nil, fmt.Errorf("missing name")
+	}
+
+	// The keys around the letters are the same on every layout
+	add(" ", " ", Key{Row: RowSpace, Finger: Thumb})
+	add("\n", "\n", Key{Row: RowHome, Column: len(layout.Keys(RowHome)), Finger: RightPinky, Hand: Right})
+	add("\t", "\t", Key{Row: RowTop, Column: -1, Finger: LeftPinky, Hand: Left})
+	return layout, nil
+}
+
+// Get returns the layout with an ID
+func Get(id string) (*Layout, bool) {
+	layout, ok := layouts[id]
+	return layout, ok
+}
+
+// All returns every layout, ordered by name
+func All() []*Layout {
+	var all []*Layout
+	for _, layout := range layouts {
+		all = append(all, layout)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
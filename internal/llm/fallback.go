@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/textgen"
+)
+
+// FallbackProvider serves canned texts and builds practice texts locally,
+// without calling any remote API
+type FallbackProvider struct{}
+
+// NewFallbackProvider creates a new fallback provider
+func NewFallbackProvider() *FallbackProvider {
+	return &FallbackProvider{}
+}
+
+// Name returns the provider name
+func (g *FallbackProvider) Name() string {
+	return "fallback"
+}
+
+// GenerateText generates text based on a prompt without network access
+func (g *FallbackProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	if strings.Contains(prompt, "AT LEAST") || strings.Contains(prompt, "Practice:") {
+		return g.generatePracticeText(prompt)
+	}
+	return g.generateRegularText(prompt)
+}
+
+// generatePracticeText creates a practice text with repeated words (fallback method)
+func (g *FallbackProvider) generatePracticeText(prompt string) (string, error) {
+	// Extract words from the prompt
+	words := extractWordsFromPrompt(prompt)
+	if len(words) == 0 {
+		return "Please provide words to practice.", nil
+	}
+
+	// Create sentences that use these words multiple times
+	sentences := []string{}
+	templates := []string{
+		"I need to practice typing the word %s correctly.",
+		"The word %s is challenging for me to type accurately.",
+		"When I type %s, I should focus on each letter carefully.",
+		"Typing %s requires attention to detail and precision.",
+		"I will improve my accuracy when typing %s with practice.",
+		"The more I practice typing %s, the better I will become.",
+		"Each time I type %s, I should check for errors.",
+		"Careful typing of %s will help me build muscle memory.",
+		"I should slow down when typing %s to avoid mistakes.",
+		"Repetition of typing %s will help me master it.",
+	}
+
+	// Generate 3 sentences for each word
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, word := range words {
+		// Use each template at least once for this word
+		for i := 0; i < 3; i++ {
+			templateIndex := r.Intn(len(templates))
+			sentence := fmt.Sprintf(templates[templateIndex], word)
+			sentences = append(sentences, sentence)
+		}
+	}
+
+	// Mix the sentences
+	r.Shuffle(len(sentences), func(i, j int) {
+		sentences[i], sentences[j] = sentences[j], sentences[i]
+	})
+
+	// Add some connecting phrases between groups of sentences
+	connectors := []string{
+		"Let's continue practicing. ",
+		"Moving on to more practice. ",
+		"Now for some more typing practice. ",
+		"Let's focus on these words again. ",
+		"Continuing with our practice session. ",
+	}
+
+	// Build the final text with connectors between groups of sentences
+	var result strings.Builder
+	for i := 0; i < len(sentences); i++ {
+		if i > 0 && i%3 == 0 {
+			result.WriteString(connectors[r.Intn(len(connectors))])
+		}
+		result.WriteString(sentences[i] + " ")
+	}
+
+	return result.String(), nil
+}
+
+// generateRegularText builds a varied text from the offline word lists. Prompts
+// asking for code still get the canned code snippet.
+func (g *FallbackProvider) generateRegularText(prompt string) (string, error) {
+	prompt = strings.ToLower(prompt)
+
+	if strings.Contains(prompt, "python") || strings.Contains(prompt, "code") || strings.Contains(prompt, "programming") {
+		return codingText, nil
+	}
+
+	return textgen.Generate(textgen.DefaultOptions(time.Now().UnixNano())), nil
+}
+
+// extractWordsFromPrompt extracts practice words from a prompt
+func extractWordsFromPrompt(prompt string) []string {
+	fmt.Printf("Extracting words from prompt: %s\n", prompt)
+
+	// For the practice text from HandleGeneratePractice, extract words from the AT LEAST part
+	if strings.Contains(prompt, "AT LEAST") {
+		parts := strings.Split(prompt, "AT LEAST")
+		if len(parts) >= 2 {
+			// Extract the words after "AT LEAST" and before the period
+			wordsPart := strings.Split(parts[1], ":")
+			if len(wordsPart) >= 2 {
+				wordsList := strings.Split(wordsPart[1], ".")
+				if len(wordsList) >= 1 {
+					cleanWords := strings.TrimSpace(wordsList[0])
+					wordList := strings.Split(cleanWords, ",")
+
+					// Clean up each word
+					var result []string
+					for _, word := range wordList {
+						word = strings.TrimSpace(word)
+						if word != "" {
+							fmt.Printf("Found practice word: %s\n", word)
+							result = append(result, word)
+						}
+					}
+					return result
+				}
+			}
+		}
+	}
+
+	// Check if this is a practice prompt with the format "Practice: word1, word2"
+	if strings.Contains(prompt, "Practice:") {
+		// Extract the words part
+		parts := strings.SplitN(prompt, "Practice:", 2)
+		if len(parts) < 2 {
+			return []string{}
+		}
+
+		// Split by commas and clean up
+		wordPart := parts[1]
+		wordList := strings.Split(wordPart, ",")
+
+		// Clean up each word
+		var result []string
+		for _, word := range wordList {
+			word = strings.TrimSpace(word)
+			if word != "" {
+				fmt.Printf("Found practice word: %s\n", word)
+				result = append(result, word)
+			}
+		}
+
+		return result
+	}
+
+	return []string{}
+}
+
+// Predefined code snippet for programming prompts (used as fallback)
+var codingText = `def calculate_fibonacci(n):
+    """
+    Calculate the Fibonacci sequence up to the nth term.
+    The Fibonacci sequence starts with 0 and 1, and each subsequent number
+    is the sum of the two preceding ones.
+    
+    Args:
+        n: The number of terms to calculate
+        
+    Returns:
+        A list containing the Fibonacci sequence
+    """
+    if n <= 0:
+        return []
+    elif n == 1:
+        return [0]
+    
+    fibonacci = [0, 1]
+    for i in range(2, n):
+        fibonacci.append(fibonacci[i-1] + fibonacci[i-2])
+    
+    return fibonacci`
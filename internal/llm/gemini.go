@@ -0,0 +1,213 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultGeminiBaseURL is the root of Google's Gemini API
+const DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiProvider generates text with Google's Gemini API
+type GeminiProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+// NewGeminiProvider creates a new Gemini provider. An empty base URL selects
+// Google's API and an empty model the default model.
+func NewGeminiProvider(baseURL, apiKey, model string) *GeminiProvider {
+	if baseURL == "" {
+		baseURL = DefaultGeminiBaseURL
+	}
+	if model == "" {
+		model = "gemini-1.5-flash"
+	}
+	return &GeminiProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+	}
+}
+
+// Name returns the provider name
+func (g *GeminiProvider) Name() string {
+	return "gemini"
+}
+
+// GeminiRequest represents a request to the Gemini API
+type GeminiRequest struct {
+	Contents []GeminiContent `json:"contents"`
+}
+
+// GeminiContent represents the content of a Gemini request
+type GeminiContent struct {
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart represents a part of a Gemini content
+type GeminiPart struct {
+	Text string `json:"text"`
+}
+
+// GeminiResponse represents a response from the Gemini API
+type GeminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+}
+
+// text returns the text of the first candidate
+func (r GeminiResponse) text() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		b.WriteString(part.Text)
+	}
+	return b.String()
+}
+
+// newRequest creates a request to a method of the model with the prompt.
+// The API key is added to the query parameters.
+func (g *GeminiProvider) newRequest(ctx context.Context, method string, params url.Values, prompt string) (*http.Request, error) {
+	requestBody := GeminiRequest{
+		Contents: []GeminiContent{
+			{
+				Parts: []GeminiPart{
+					{
+						Text: prompt,
+					},
+				},
+			},
+		},
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	params.Set("key", g.apiKey)
+	endpoint := fmt.Sprintf("%s/models/%s:%s?%s", g.baseURL, g.model, method, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+// GenerateText generates text using the Gemini API
+func (g *GeminiProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	// Enhance the prompt with typing-specific instructions
+	enhancedPrompt := enhancePromptForTyping(prompt)
+
+	fmt.Printf("Calling Gemini API with enhanced prompt: %s\n", enhancedPrompt)
+	req, err := g.newRequest(ctx, "generateContent", url.Values{}, enhancedPrompt)
+	if err != nil {
+		return "", err
+	}
+
+	// Send the request
+	fmt.Println("Sending request to Gemini API...")
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Read the response
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response: %v", err)
+	}
+
+	fmt.Printf("Response status: %d\n", resp.StatusCode)
+	fmt.Printf("Response body: %s\n", string(body))
+
+	// Check for non-200 status code
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// Parse the response
+	var geminiResponse GeminiResponse
+	if err := json.Unmarshal(body, &geminiResponse); err != nil {
+		return "", fmt.Errorf("error parsing response: %v", err)
+	}
+
+	// Extract the generated text
+	if generatedText := geminiResponse.text(); generatedText != "" {
+		fmt.Printf("Successfully generated text (%d characters)\n", len(generatedText))
+		return generatedText, nil
+	}
+
+	return "", fmt.Errorf("no text generated in response")
+}
+
+// StreamText generates text with the streaming endpoint of the Gemini API,
+// which sends a response with the next part of the text as a server-sent
+// event while the text is generated
+func (g *GeminiProvider) StreamText(ctx context.Context, prompt string, emit func(chunk string) error) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, StreamTimeout)
+	defer cancel()
+
+	req, err := g.newRequest(ctx, "streamGenerateContent", url.Values{"alt": {"sse"}}, enhancePromptForTyping(prompt))
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Println("Streaming from Gemini API...")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var text strings.Builder
+	err = readEvents(resp.Body, func(data string) error {
+		if err := eventError(data); err != nil {
+			return err
+		}
+
+		var geminiResponse GeminiResponse
+		if err := json.Unmarshal([]byte(data), &geminiResponse); err != nil {
+			return fmt.Errorf("error parsing response: %v", err)
+		}
+		chunk := geminiResponse.text()
+		if chunk == "" {
+			return nil
+		}
+		text.WriteString(chunk)
+		return emit(chunk)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no text generated in response")
+	}
+	fmt.Printf("Successfully streamed text (%d characters)\n", text.Len())
+	return text.String(), nil
+}
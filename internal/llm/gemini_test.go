@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// geminiChunk is a streamed Gemini response with a part of the text
+func geminiChunk(text string) string {
+	return `{"candidates":[{"content":{"parts":[{"text":"` + text + `"}],"role":"model"}}]}`
+}
+
+func TestGeminiStreamText(t *testing.T) {
+	server, request := sseServer(t,
+		geminiChunk("The quick brown "),
+		geminiChunk("fox jumps."),
+		`{"candidates":[{"content":{"parts":[{"text":""}]},"finishReason":"STOP"}]}`,
+	)
+
+	chunks, text, err := collect(NewGeminiProvider(server.URL, "secret", "gemini-test"))
+	if err != nil {
+		t.Fatalf("StreamText: %v", err)
+	}
+	if !slices.Equal(chunks, []string{"The quick brown ", "fox jumps."}) {
+		t.Errorf("chunks = %q", chunks)
+	}
+	if text != "The quick brown fox jumps." {
+		t.Errorf("text = %q", text)
+	}
+
+	if request.URL.Path != "/models/gemini-test:streamGenerateContent" {
+		t.Errorf("path = %q", request.URL.Path)
+	}
+	if query := request.URL.Query(); query.Get("alt") != "sse" || query.Get("key") != "secret" {
+		t.Errorf("query = %q, want alt=sse and the key", request.URL.RawQuery)
+	}
+}
+
+func TestGeminiStreamTextErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider func(t *testing.T) *GeminiProvider
+		message  string
+	}{
+		{
+			name: "error event",
+			provider: func(t *testing.T) *GeminiProvider {
+				server, _ := sseServer(t, geminiChunk("Start"), `{"error":{"code":503,"message":"The model is overloaded.","status":"UNAVAILABLE"}}`)
+				return NewGeminiProvider(server.URL, "key", "model")
+			},
+			message: "The model is overloaded.",
+		},
+		{
+			name: "malformed event",
+			provider: func(t *testing.T) *GeminiProvider {
+				server, _ := sseServer(t, `{"candidates":`)
+				return NewGeminiProvider(server.URL, "key", "model")
+			},
+			message: "error parsing response",
+		},
+		{
+			name: "no text",
+			provider: func(t *testing.T) *GeminiProvider {
+				server, _ := sseServer(t, `{"candidates":[]}`)
+				return NewGeminiProvider(server.URL, "key", "model")
+			},
+			message: "no text generated",
+		},
+		{
+			name: "mid-stream disconnect",
+			provider: func(t *testing.T) *GeminiProvider {
+				server := disconnectingServer(t, geminiChunk("Cut"))
+				return NewGeminiProvider(server.URL, "key", "model")
+			},
+			message: "unexpected EOF",
+		},
+		{
+			name: "server unreachable",
+			provider: func(t *testing.T) *GeminiProvider {
+				server, _ := sseServer(t)
+				server.Close()
+				return NewGeminiProvider(server.URL, "key", "model")
+			},
+			message: "error sending request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := collect(tt.provider(t))
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.message)
+			}
+		})
+	}
+}
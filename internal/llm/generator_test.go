@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider is a provider that can't stream. It returns a fixed text or
+// error and records the prompts it was asked for.
+type fakeProvider struct {
+	text    string
+	err     error
+	prompts *[]string
+}
+
+func (p fakeProvider) Name() string { return "fake" }
+
+func (p fakeProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	if p.prompts != nil {
+		*p.prompts = append(*p.prompts, prompt)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return p.text, p.err
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string // the provider name, or a part of the error
+	}{
+		{"fallback without configuration", Config{}, "fallback"},
+		{"gemini when a key is set", Config{GeminiAPIKey: "key"}, "gemini"},
+		{"explicit fallback despite a key", Config{Provider: "Fallback", GeminiAPIKey: "key"}, "fallback"},
+		{"openai", Config{Provider: "openai", OpenAIBaseURL: "http://localhost:1234/v1", OpenAIModel: "model"}, "openai"},
+		{"gemini without a key", Config{Provider: "gemini"}, "requires an API key"},
+		{"openai without a base URL", Config{Provider: "openai", OpenAIModel: "model"}, "requires a base URL"},
+		{"openai without a model", Config{Provider: "openai", OpenAIBaseURL: "http://localhost:1234/v1"}, "requires a model name"},
+		{"unknown provider", Config{Provider: "claude"}, "unknown LLM provider"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewProvider(tt.cfg)
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("err = %v, want it to contain %q", err, tt.want)
+				}
+				return
+			}
+			if provider.Name() != tt.want {
+				t.Errorf("provider = %q, want %q", provider.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTextGenerator(t *testing.T) {
+	var prompts []string
+	generator := NewTextGenerator(fakeProvider{text: "Some text.", prompts: &prompts})
+	if generator.Name() != "fake" {
+		t.Errorf("Name() = %q, want the provider's name", generator.Name())
+	}
+
+	text, err := generator.GenerateText(context.Background(), "prompt")
+	if err != nil || text != "Some text." {
+		t.Errorf("GenerateText = %q, %v", text, err)
+	}
+	if len(prompts) != 1 || prompts[0] != "prompt" {
+		t.Errorf("prompts = %q, want the prompt passed on", prompts)
+	}
+
+	failed := errors.New("quota exceeded")
+	if _, err := NewTextGenerator(fakeProvider{err: failed}).GenerateText(context.Background(), "prompt"); !errors.Is(err, failed) {
+		t.Errorf("err = %v, want %v", err, failed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := generator.GenerateText(ctx, "prompt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want the context's error", err)
+	}
+}
+
+func TestFallbackGenerateText(t *testing.T) {
+	provider := NewFallbackProvider()
+	for _, prompt := range []string{"Tell me about the ocean", "Practice: apple, river"} {
+		text, err := provider.GenerateText(context.Background(), prompt)
+		if err != nil || strings.TrimSpace(text) == "" {
+			t.Errorf("GenerateText(%q) = %q, %v; want a text", prompt, text, err)
+		}
+	}
+}
+
+func TestGenerateTextCanceled(t *testing.T) {
+	// Never answers before the test ends
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	providers := []Provider{
+		NewGeminiProvider(server.URL, "key", "model"),
+		NewOpenAIProvider(server.URL, "", "model"),
+	}
+	for _, provider := range providers {
+		t.Run(provider.Name(), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := provider.GenerateText(ctx, "prompt")
+			if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+				t.Errorf("err = %v, want the context's error", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("GenerateText returned after %v, want it to stop with the context", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider generates text with an OpenAI-compatible chat completions
+// endpoint, such as a local llama.cpp or Ollama server
+type OpenAIProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+// NewOpenAIProvider creates a new provider for an OpenAI-compatible server.
+// baseURL is the API root, e.g. http://localhost:11434/v1
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	return &OpenAIProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+	}
+}
+
+// Name returns the provider name
+func (o *OpenAIProvider) Name() string {
+	return "openai"
+}
+
+// ChatMessage represents a single message of a chat completion request
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatRequest represents a request to a chat completions endpoint
+type ChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	Stream   bool          `json:"stream,omitempty"`
+}
+
+// ChatResponse represents a response from a chat completions endpoint
+type ChatResponse struct {
+	Choices []struct {
+		Message ChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// ChatChunk represents an event of a streamed chat completion
+type ChatChunk struct {
+	Choices []struct {
+		Delta ChatMessage `json:"delta"`
+	} `json:"choices"`
+}
+
+// newRequest creates a chat completions request with the prompt
+func (o *OpenAIProvider) newRequest(ctx context.Context, prompt string, stream bool) (*http.Request, error) {
+	requestBody := ChatRequest{
+		Model: o.model,
+		Messages: []ChatMessage{
+			{Role: "user", Content: prompt},
+		},
+		Stream: stream,
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	return req, nil
+}
+
+// GenerateText generates text using the chat completions endpoint
+func (o *OpenAIProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	// Enhance the prompt with typing-specific instructions
+	enhancedPrompt := enhancePromptForTyping(prompt)
+
+	req, err := o.newRequest(ctx, enhancedPrompt, false)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Sending request to %s (model %s)...\n", req.URL, o.model)
+	// Local models can be slow to answer on modest hardware
+	client := &http.Client{Timeout: 120 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var chatResponse ChatResponse
+	if err := json.Unmarshal(body, &chatResponse); err != nil {
+		return "", fmt.Errorf("error parsing response: %v", err)
+	}
+
+	if len(chatResponse.Choices) > 0 && chatResponse.Choices[0].Message.Content != "" {
+		generatedText := chatResponse.Choices[0].Message.Content
+		fmt.Printf("Successfully generated text (%d characters)\n", len(generatedText))
+		return generatedText, nil
+	}
+
+	return "", fmt.Errorf("no text generated in response")
+}
+
+// StreamText generates text with a streamed chat completion, which sends the
+// next part of the text as a server-sent event while it is generated
+func (o *OpenAIProvider) StreamText(ctx context.Context, prompt string, emit func(chunk string) error) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, StreamTimeout)
+	defer cancel()
+
+	req, err := o.newRequest(ctx, enhancePromptForTyping(prompt), true)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Streaming from %s (model %s)...\n", req.URL, o.model)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var text strings.Builder
+	err = readEvents(resp.Body, func(data string) error {
+		// The server ends the stream with a [DONE] event
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+		if err := eventError(data); err != nil {
+			return err
+		}
+
+		var chunk ChatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("error parsing response: %v", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			return nil
+		}
+		text.WriteString(chunk.Choices[0].Delta.Content)
+		return emit(chunk.Choices[0].Delta.Content)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no text generated in response")
+	}
+	fmt.Printf("Successfully streamed text (%d characters)\n", text.Len())
+	return text.String(), nil
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// chatChunk is a streamed chat completion event with a part of the text
+func chatChunk(text string) string {
+	return `{"id":"1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"` + text + `"}}]}`
+}
+
+func TestOpenAIStreamText(t *testing.T) {
+	var body ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, `data: {"choices":[{"delta":{"role":"assistant"}}]}`+"\n\n")
+		io.WriteString(w, "data: "+chatChunk("Hello ")+"\n\n")
+		io.WriteString(w, ": processing\n\n")
+		io.WriteString(w, "data: "+chatChunk("world.")+"\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+		// Anything after [DONE] is ignored
+		io.WriteString(w, "data: "+chatChunk(" Ignored.")+"\n\n")
+	}))
+	defer server.Close()
+
+	chunks, text, err := collect(NewOpenAIProvider(server.URL, "secret", "local-model"))
+	if err != nil {
+		t.Fatalf("StreamText: %v", err)
+	}
+	if !slices.Equal(chunks, []string{"Hello ", "world."}) {
+		t.Errorf("chunks = %q", chunks)
+	}
+	if text != "Hello world." {
+		t.Errorf("text = %q", text)
+	}
+	if !body.Stream || body.Model != "local-model" {
+		t.Errorf("request = %+v, want a streamed request for the model", body)
+	}
+}
+
+func TestOpenAIStreamTextErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider func(t *testing.T) *OpenAIProvider
+		message  string
+	}{
+		{
+			name: "error event",
+			provider: func(t *testing.T) *OpenAIProvider {
+				server, _ := sseServer(t, chatChunk("Start"), `{"error":{"message":"context length exceeded","type":"invalid_request_error"}}`)
+				return NewOpenAIProvider(server.URL, "", "model")
+			},
+			message: "context length exceeded",
+		},
+		{
+			name: "only done",
+			provider: func(t *testing.T) *OpenAIProvider {
+				server, _ := sseServer(t, "[DONE]")
+				return NewOpenAIProvider(server.URL, "", "model")
+			},
+			message: "no text generated",
+		},
+		{
+			name: "mid-stream disconnect",
+			provider: func(t *testing.T) *OpenAIProvider {
+				server := disconnectingServer(t, chatChunk("Cut"))
+				return NewOpenAIProvider(server.URL, "", "model")
+			},
+			message: "unexpected EOF",
+		},
+		{
+			name: "status error",
+			provider: func(t *testing.T) *OpenAIProvider {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+				}))
+				t.Cleanup(server.Close)
+				return NewOpenAIProvider(server.URL, "", "model")
+			},
+			message: "status 503",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := collect(tt.provider(t))
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// StreamTimeout bounds a streamed generation. It is longer than the timeout
+// of a single response, since the text arrives while it is generated.
+const StreamTimeout = 2 * time.Minute
+
+// StreamProvider is a Provider that can stream text while it is generated
+type StreamProvider interface {
+	Provider
+	// StreamText generates text based on a prompt, passes every chunk to emit
+	// as it arrives and returns the complete text. An error returned by emit
+	// stops the stream.
+	StreamText(ctx context.Context, prompt string, emit func(chunk string) error) (string, error)
+}
+
+// StreamText streams text from the provider. Providers that can't stream
+// generate the whole text and emit it as a single chunk, and so do providers
+// whose stream fails before its first chunk, e.g. servers without a
+// streaming endpoint. A TextGenerator already falls back for its provider,
+// so its error is returned as it is.
+func StreamText(ctx context.Context, provider Provider, prompt string, emit func(chunk string) error) (string, error) {
+	if generator, ok := provider.(*TextGenerator); ok {
+		return generator.StreamText(ctx, prompt, emit)
+	}
+	if streamer, ok := provider.(StreamProvider); ok {
+		started := false
+		text, err := streamer.StreamText(ctx, prompt, func(chunk string) error {
+			started = true
+			return emit(chunk)
+		})
+		if err == nil || started || ctx.Err() != nil {
+			return text, err
+		}
+		fmt.Printf("Streaming failed, generating without streaming: %v\n", err)
+	}
+
+	text, err := provider.GenerateText(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	if err := emit(text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// eventError returns the error an API sends as an event of a stream when
+// generation fails after the stream started, or nil for any other event
+func eventError(data string) error {
+	var event struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(data), &event) != nil || event.Error == nil {
+		return nil
+	}
+	return fmt.Errorf("API error: %s", event.Error.Message)
+}
+
+// errStreamDone ends reading an event stream early without an error
+var errStreamDone = errors.New("end of stream")
+
+// readEvents reads a server-sent event stream and passes the data of every
+// event to handle, until the stream ends or handle returns an error.
+// Returning errStreamDone stops reading without an error.
+func readEvents(r io.Reader, handle func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			return nil
+		}
+		event := strings.Join(data, "\n")
+		data = nil
+		return handle(event)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line ends the event
+			if err := dispatch(); errors.Is(err, errStreamDone) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := dispatch(); !errors.Is(err, errStreamDone) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		events []string
+	}{
+		{
+			name:   "single line events",
+			stream: "data: one\n\ndata: two\n\n",
+			events: []string{"one", "two"},
+		},
+		{
+			name:   "multi-line data is joined with line breaks",
+			stream: "data: first line\ndata: second line\n\ndata: next\n\n",
+			events: []string{"first line\nsecond line", "next"},
+		},
+		{
+			name:   "the space after the colon is optional",
+			stream: "data:tight\n\ndata:  padded\n\n",
+			events: []string{"tight", " padded"},
+		},
+		{
+			name:   "comments, event names and ids are skipped",
+			stream: ": keep-alive\nevent: message\nid: 7\ndata: text\nretry: 1000\n\n",
+			events: []string{"text"},
+		},
+		{
+			name:   "crlf line endings",
+			stream: "data: one\r\n\r\ndata: two\r\n\r\n",
+			events: []string{"one", "two"},
+		},
+		{
+			name:   "last event without a blank line",
+			stream: "data: one\n\ndata: two",
+			events: []string{"one", "two"},
+		},
+		{
+			name:   "blank lines without data",
+			stream: "\n\ndata: one\n\n\n\n",
+			events: []string{"one"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			err := readEvents(strings.NewReader(tt.stream), func(data string) error {
+				events = append(events, data)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("readEvents: %v", err)
+			}
+			if !slices.Equal(events, tt.events) {
+				t.Errorf("events = %q, want %q", events, tt.events)
+			}
+		})
+	}
+}
+
+func TestReadEventsStops(t *testing.T) {
+	stream := "data: one\n\ndata: [DONE]\n\ndata: after\n\n"
+
+	var events []string
+	err := readEvents(strings.NewReader(stream), func(data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+		events = append(events, data)
+		return nil
+	})
+	if err != nil || !slices.Equal(events, []string{"one"}) {
+		t.Errorf("done: events = %q, err = %v; want [one] and no error", events, err)
+	}
+
+	failed := errors.New("failed")
+	err = readEvents(strings.NewReader(stream), func(data string) error { return failed })
+	if !errors.Is(err, failed) {
+		t.Errorf("error: err = %v, want %v", err, failed)
+	}
+}
+
+// sseServer serves a server-sent event stream of the given events and
+// records the request
+func sseServer(t *testing.T, events ...string) (*httptest.Server, *http.Request) {
+	t.Helper()
+	var received http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = *r.Clone(context.Background())
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+// disconnectingServer writes the events and then drops the connection in
+// the middle of the response
+func disconnectingServer(t *testing.T, events ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// collect streams text and returns the emitted chunks along with the result
+func collect(streamer StreamProvider) ([]string, string, error) {
+	var chunks []string
+	text, err := streamer.StreamText(context.Background(), "prompt", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	return chunks, text, err
+}
+
+func TestStreamTextFallback(t *testing.T) {
+	// Serves generateContent, but has no streaming endpoint
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ":streamGenerateContent") {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"text":"Whole text."}]}}]}`)
+	}))
+	defer server.Close()
+
+	t.Run("stream fails before the first chunk", func(t *testing.T) {
+		var chunks []string
+		text, err := StreamText(context.Background(), NewGeminiProvider(server.URL, "key", "model"), "prompt", func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		if err != nil || text != "Whole text." || !slices.Equal(chunks, []string{"Whole text."}) {
+			t.Errorf("text = %q, chunks = %q, err = %v; want the whole text as one chunk", text, chunks, err)
+		}
+	})
+
+	t.Run("stream fails after a chunk", func(t *testing.T) {
+		server := disconnectingServer(t, `{"candidates":[{"content":{"parts":[{"text":"Half"}]}}]}`)
+		var chunks []string
+		_, err := StreamText(context.Background(), NewGeminiProvider(server.URL, "key", "model"), "prompt", func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		if err == nil || !slices.Equal(chunks, []string{"Half"}) {
+			t.Errorf("chunks = %q, err = %v; want the error without generating again", chunks, err)
+		}
+	})
+
+	t.Run("emit fails", func(t *testing.T) {
+		server, _ := sseServer(t, `{"candidates":[{"content":{"parts":[{"text":"One"}]}}]}`)
+		gone := errors.New("client gone")
+		calls := 0
+		_, err := StreamText(context.Background(), NewGeminiProvider(server.URL, "key", "model"), "prompt", func(chunk string) error {
+			calls++
+			return gone
+		})
+		if !errors.Is(err, gone) || calls != 1 {
+			t.Errorf("err = %v after %d chunks, want %v after 1", err, calls, gone)
+		}
+	})
+
+	t.Run("provider without streaming", func(t *testing.T) {
+		var chunks []string
+		text, err := StreamText(context.Background(), fakeProvider{text: "Text."}, "prompt", func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		if err != nil || text != "Text." || !slices.Equal(chunks, []string{"Text."}) {
+			t.Errorf("text = %q, chunks = %q, err = %v", text, chunks, err)
+		}
+
+		failed := errors.New("unavailable")
+		if _, err := StreamText(context.Background(), fakeProvider{err: failed}, "prompt", func(string) error { return nil }); !errors.Is(err, failed) {
+			t.Errorf("err = %v, want %v", err, failed)
+		}
+	})
+}
+
+// failingStreamer counts its generations, which all fail
+type failingStreamer struct {
+	generations int
+}
+
+func (p *failingStreamer) Name() string { return "failing" }
+
+func (p *failingStreamer) GenerateText(ctx context.Context, prompt string) (string, error) {
+	p.generations++
+	return "", errors.New("unavailable")
+}
+
+func (p *failingStreamer) StreamText(ctx context.Context, prompt string, emit func(chunk string) error) (string, error) {
+	p.generations++
+	return "", errors.New("unavailable")
+}
+
+func TestStreamTextGeneratorFallsBackOnce(t *testing.T) {
+	provider := &failingStreamer{}
+	_, err := StreamText(context.Background(), NewTextGenerator(provider), "prompt", func(string) error { return nil })
+	if err == nil {
+		t.Fatal("StreamText succeeded, want the provider's error")
+	}
+	if provider.generations != 2 {
+		t.Errorf("%d generations, want the stream and a single fallback", provider.generations)
+	}
+}
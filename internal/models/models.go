@@ -1,63 +1,430 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// Text kinds
+const (
+	TextKindProse = "prose"
+	TextKindCode  = "code"
+)
+
+// Text sources, recording how a text was made
+const (
+	TextSourceLLM      = "llm"
+	TextSourceOffline  = "offline"
+	TextSourcePractice = "practice"
+	TextSourceAdaptive = "adaptive"
+	TextSourceCode     = "code"
+	TextSourceCustom   = "custom"
+	TextSourceImport   = "import"
+	TextSourceLesson   = "lesson"
+)
+
+// TextSources lists every text source
+var TextSources = []string{
+	TextSourceLLM, TextSourceOffline, TextSourcePractice, TextSourceAdaptive,
+	TextSourceCode, TextSourceCustom, TextSourceImport, TextSourceLesson,
+}
+
+// Difficulty levels of texts
+const (
+	DifficultyEasy   = 1
+	DifficultyMedium = 2
+	DifficultyHard   = 3
+)
+
+// DifficultyNames are the names of the difficulty levels, indexed by level
+var DifficultyNames = []string{"", "easy", "medium", "hard"}
+
+// LengthRange is a named range of text lengths in characters. A zero Max is
+// open.
+type LengthRange struct {
+	Name string
+	Min  int
+	Max  int
+}
+
+// TextLengths are the length buckets texts are grouped into
+var TextLengths = []LengthRange{
+	{Name: "short", Min: 0, Max: 150},
+	{Name: "medium", Min: 151, Max: 400},
+	{Name: "long", Min: 401},
+}
+
+// LengthOf returns the name of the length bucket of a text of n characters
+func LengthOf(n int) string {
+	for _, r := range TextLengths {
+		if n >= r.Min && (r.Max == 0 || n <= r.Max) {
+			return r.Name
+		}
+	}
+	return ""
+}
 
 // Text represents a typing exercise text
 type Text struct {
-	ID        int64
-	Content   string
-	Prompt    string
-	CreatedAt time.Time
+	ID         int64  `json:"id"`
+	Content    string `json:"content"`
+	Prompt     string `json:"prompt"`
+	Kind       string `json:"kind"`
+	Language   string `json:"language,omitempty"` // code texts only
+	Source     string `json:"source"`
+	Difficulty int    `json:"difficulty"`
+
+	CollectionID int64     `json:"collection_id,omitempty"` // imported passages only
+	Passage      int       `json:"passage,omitempty"`       // 1-based position in the collection
+	Lesson       string    `json:"lesson,omitempty"`        // lesson texts only
+	Streamed     bool      `json:"streamed,omitempty"`      // grows while a timed or sudden death test is typed
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// LibraryText is a text in a user's library, with the user's tags and how
+// often and how fast the user typed it
+type LibraryText struct {
+	Text
+	Tags     []string `json:"tags"`
+	Sessions int      `json:"sessions"`
+	BestWPM  float64  `json:"best_wpm"`
+}
+
+// Collection is an imported file split into passages
+type Collection struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"` // name of the imported file
+	Format    string    `json:"format"`
+	Passages  int       `json:"passages"`
+	Completed int       `json:"completed"` // last passage the user finished
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// User represents a Figure10 account
+type User struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
-// Session represents a typing session
+// UserSettings holds a user's preferences
+type UserSettings struct {
+	UserID           int64  `json:"-"`
+	StripMarkdown    bool   `json:"strip_markdown"`
+	ASCIIPunctuation bool   `json:"ascii_punctuation"`
+	MaxLength        int    `json:"max_length"`
+	AllowedChars     string `json:"allowed_chars"`
+	Layout           string `json:"layout"`
+	ShowKeyboard     bool   `json:"show_keyboard"`
+}
+
+// Session represents a typing session. CompletedAt is zero while the session
+// is in progress.
 type Session struct {
-	ID          int64
-	TextID      int64
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"-"`
+	TextID      int64     `json:"text_id"`
+	WPM         float64   `json:"wpm"`
+	Accuracy    float64   `json:"accuracy"`
+	Errors      int       `json:"errors"`
+	CompletedAt time.Time `json:"completed_at"`
+	Test        Test      `json:"test"`
+	Metrics
+}
+
+// Test modes decide when a session ends
+const (
+	TestText        = "text"         // when the whole text is typed
+	TestTime        = "time"         // when the time limit is up
+	TestWords       = "words"        // when a text of a fixed number of words is typed
+	TestSuddenDeath = "sudden-death" // at the first wrong key
+)
+
+// Parameters of the timed and word count tests
+var (
+	TestDurations  = []int{15, 30, 60, 120} // seconds
+	TestWordCounts = []int{10, 25, 50, 100}
+)
+
+// Test is the test mode of a session. Param is the time limit in seconds of
+// timed tests and the number of words of word count tests, and zero for the
+// other modes. Results are only comparable within the same test.
+type Test struct {
+	Mode  string `json:"mode"`
+	Param int    `json:"param"`
+}
+
+// Tests lists every test that can be taken, in the order they are offered
+func Tests() []Test {
+	tests := []Test{{Mode: TestText}}
+	for _, seconds := range TestDurations {
+		tests = append(tests, Test{Mode: TestTime, Param: seconds})
+	}
+	for _, words := range TestWordCounts {
+		tests = append(tests, Test{Mode: TestWords, Param: words})
+	}
+	return append(tests, Test{Mode: TestSuddenDeath})
+}
+
+// Key identifies the test in URLs and forms, e.g. "time:60"
+func (t Test) Key() string {
+	if t.Param == 0 {
+		return t.Mode
+	}
+	return fmt.Sprintf("%s:%d", t.Mode, t.Param)
+}
+
+// Name describes the test for display, e.g. "60 seconds"
+func (t Test) Name() string {
+	switch t.Mode {
+	case TestTime:
+		return fmt.Sprintf("%d seconds", t.Param)
+	case TestWords:
+		return fmt.Sprintf("%d words", t.Param)
+	case TestSuddenDeath:
+		return "Sudden death"
+	}
+	return "Full text"
+}
+
+// Streamed reports whether the test types an endless stream of text that is
+// extended while typing
+func (t Test) Streamed() bool {
+	return t.Mode == TestTime || t.Mode == TestSuddenDeath
+}
+
+// Metrics are the standard typing test measures of a session, computed from
+// its keystroke log. Corrected mistakes lower the keystroke accuracy but not
+// the net speed; mistakes left in the input lower both. Sessions completed
+// without a keystroke log have zero metrics.
+type Metrics struct {
+	RawWPM            float64 `json:"raw_wpm"`            // every typed character, right or wrong
+	NetWPM            float64 `json:"net_wpm"`            // raw speed less the uncorrected errors per minute
+	KeystrokeAccuracy float64 `json:"keystroke_accuracy"` // percentage of key presses that were right
+	UncorrectedErrors int     `json:"uncorrected_errors"` // wrong characters left in the input
+
+	// Consistency is the coefficient of variation of the per-second speed
+	// in percent. Lower is steadier.
+	Consistency float64 `json:"consistency"`
+}
+
+// SessionSample is a completed session as it enters the progress statistics
+type SessionSample struct {
+	CompletedAt time.Time
+	Mode        string
 	WPM         float64
 	Accuracy    float64
-	Errors      int
-	CompletedAt time.Time
+	Duration    time.Duration // time between the first and the last keystroke
+	Characters  int           // keys pressed, not counting backspace
 }
 
-// SessionWithText extends Session with the text prompt
+// SessionWithText extends Session with the text prompt and language
 type SessionWithText struct {
 	Session
-	Prompt string
+	Prompt   string `json:"prompt"`
+	Language string `json:"language,omitempty"`
+}
+
+// SessionDetail is a session together with its text and the errors made
+type SessionDetail struct {
+	Session Session       `json:"session"`
+	Text    Text          `json:"text"`
+	Errors  []TypingError `json:"errors"`
+}
+
+// Stats summarizes a user's completed sessions
+type Stats struct {
+	Sessions        int     `json:"sessions"`
+	AverageWPM      float64 `json:"average_wpm"`
+	BestWPM         float64 `json:"best_wpm"`
+	AverageAccuracy float64 `json:"average_accuracy"`
+	TotalErrors     int     `json:"total_errors"`
+
+	// Tests breaks the sessions down by test, as results of different tests
+	// aren't comparable
+	Tests []TestStats `json:"tests"`
+
+	// Layouts breaks the sessions down by keyboard layout, to follow the
+	// switch to a new one
+	Layouts []LayoutStats `json:"layouts"`
+}
+
+// LayoutStats summarizes a user's completed sessions on one keyboard layout
+type LayoutStats struct {
+	Layout          string    `json:"layout"`
+	Sessions        int       `json:"sessions"`
+	AverageWPM      float64   `json:"average_wpm"`
+	BestWPM         float64   `json:"best_wpm"`
+	AverageAccuracy float64   `json:"average_accuracy"`
+	FirstSession    time.Time `json:"first_session"`
+	LastSession     time.Time `json:"last_session"`
+}
+
+// TextStats summarizes a user's completed sessions on one text
+type TextStats struct {
+	Sessions        int     `json:"sessions"`
+	AverageWPM      float64 `json:"average_wpm"`
+	BestWPM         float64 `json:"best_wpm"`
+	AverageAccuracy float64 `json:"average_accuracy"`
+}
+
+// TestStats summarizes a user's completed sessions of one test
+type TestStats struct {
+	Test            Test    `json:"test"`
+	Sessions        int     `json:"sessions"`
+	AverageWPM      float64 `json:"average_wpm"`
+	BestWPM         float64 `json:"best_wpm"`
+	AverageAccuracy float64 `json:"average_accuracy"`
 }
 
 // TypingError represents a specific typing error
 type TypingError struct {
-	ID           int64
-	SessionID    int64
-	ExpectedChar string
-	TypedChar    string
-	Position     int
+	ID           int64  `json:"-"`
+	UserID       int64  `json:"-"`
+	SessionID    int64  `json:"-"`
+	ExpectedChar string `json:"expected_char"`
+	TypedChar    string `json:"typed_char"`
+	Position     int    `json:"position"`
 }
 
 // CommonError represents a common typing error
 type CommonError struct {
-	ExpectedChar string
-	TypedChar    string
-	Count        int
+	ExpectedChar string `json:"expected_char"`
+	TypedChar    string `json:"typed_char"`
+	Count        int    `json:"count"`
+}
+
+// LayoutError counts the typing errors made on an expected character on a
+// keyboard layout
+type LayoutError struct {
+	Layout       string `json:"layout"`
+	ExpectedChar string `json:"expected_char"`
+	Count        int    `json:"count"`
+}
+
+// Keystroke represents a single raw key event recorded by the client
+type Keystroke struct {
+	ID        int64  `json:"-"`
+	SessionID int64  `json:"-"`
+	Seq       int    `json:"seq"`
+	Key       string `json:"key"`
+	Timestamp int64  `json:"timestamp"` // milliseconds since the session started
+	Position  int    `json:"position"`  // cursor position before the key was applied
+	Backspace bool   `json:"backspace"`
+}
+
+// SessionKeystrokes holds the keystroke log of a completed session together
+// with the text it was typed against
+type SessionKeystrokes struct {
+	SessionID   int64
+	Content     string
+	Kind        string
+	Layout      string
+	CompletedAt time.Time
+	Keystrokes  []Keystroke
 }
 
 // TypingResult represents the result of a typing session
 type TypingResult struct {
+	SessionID    int64         `json:"session_id"`
 	TextID       int64         `json:"text_id"`
 	WPM          float64       `json:"wpm"`
 	Accuracy     float64       `json:"accuracy"`
 	Errors       int           `json:"errors"`
 	ErrorDetails []TypingError `json:"error_details"`
 	ErrorWords   []string      `json:"error_words"`
+	Finished     bool          `json:"finished"` // whether the test ran to its end
+	Test         Test          `json:"test"`
+	Metrics
+
+	// PersonalBests lists the categories in which the session set a new
+	// personal best
+	PersonalBests []PersonalBest `json:"personal_bests"`
+
+	// Lesson is how the session did on the lesson of a lesson text
+	Lesson *LessonResult `json:"lesson,omitempty"`
+}
+
+// LessonResult is how a session did on a lesson of the curriculum
+type LessonResult struct {
+	Name           string         `json:"name"`
+	Number         int            `json:"number"`
+	TargetWPM      float64        `json:"target_wpm"`
+	TargetAccuracy float64        `json:"target_accuracy"`
+	Passed         bool           `json:"passed"` // whether this session passed the lesson
+	Progress       LessonProgress `json:"progress"`
+}
+
+// LessonProgress is a user's progress through a lesson of the curriculum.
+// PassedAt is zero until a session passes the lesson.
+type LessonProgress struct {
+	Lesson       string    `json:"lesson"`
+	Attempts     int       `json:"attempts"`
+	BestWPM      float64   `json:"best_wpm"`
+	BestAccuracy float64   `json:"best_accuracy"`
+	PassedAt     time.Time `json:"passed_at"`
+}
+
+// Passed reports whether the user passed the lesson
+func (p LessonProgress) Passed() bool {
+	return !p.PassedAt.IsZero()
+}
+
+// Personal best categories. Bests are kept per text, per mode (the source a
+// text was made with), per text length bucket and overall, each separately
+// for every test.
+const (
+	BestOverall = "overall"
+	BestText    = "text"
+	BestMode    = "mode"
+	BestLength  = "length"
+)
+
+// PersonalBest is the fastest session of a user in a category. Key is the
+// text ID, mode or length bucket, and empty for the overall best.
+// PreviousWPM is set when a session beat an earlier best, and zero for a
+// first result.
+type PersonalBest struct {
+	Category    string    `json:"category"`
+	Key         string    `json:"key"`
+	SessionID   int64     `json:"session_id"`
+	TextID      int64     `json:"text_id"`
+	WPM         float64   `json:"wpm"`
+	Accuracy    float64   `json:"accuracy"`
+	PreviousWPM float64   `json:"previous_wpm,omitempty"`
+	CompletedAt time.Time `json:"completed_at"`
+	Test        Test      `json:"test"`
+}
+
+// LeaderboardEntry is the best session of a user on a leaderboard
+type LeaderboardEntry struct {
+	Rank        int       `json:"rank"`
+	Username    string    `json:"username"`
+	SessionID   int64     `json:"session_id"`
+	WPM         float64   `json:"wpm"`
+	Accuracy    float64   `json:"accuracy"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// WeeklyChallenge is the text all users race on during an ISO week, with the
+// leaderboard of the sessions typed that week
+type WeeklyChallenge struct {
+	Week        string             `json:"week"`
+	Text        Text               `json:"text"`
+	StartsAt    time.Time          `json:"starts_at"`
+	EndsAt      time.Time          `json:"ends_at"`
+	Leaderboard []LeaderboardEntry `json:"leaderboard"`
 }
 
 // TypingCheck represents a real-time typing check result
 type TypingCheck struct {
-	Correct    bool
-	CurrentWPM float64
-	CurrentAcc float64
-	CurrentPos int
-	TotalChars int
-	ErrorCount int
+	Correct    bool    `json:"correct"`
+	CurrentWPM float64 `json:"current_wpm"`
+	CurrentAcc float64 `json:"current_accuracy"`
+	CurrentPos int     `json:"current_position"`
+	TotalChars int     `json:"total_chars"`
+	ErrorCount int     `json:"error_count"`
 }
@@ -0,0 +1,215 @@
+package normalize
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/janislaus/figure10/internal/scoring"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Options control how generated text is cleaned up before it is stored
+type Options struct {
+	StripMarkdown      bool   // remove markdown markup such as **bold** and # headings
+	ASCIIPunctuation   bool   // map typographic punctuation to its ASCII equivalent
+	CollapseWhitespace bool   // turn every run of whitespace into a single space
+	MaxLength          int    // cut the text at a sentence boundary before this many characters, 0 for no limit
+	Allowed            string // the only characters allowed besides spaces, empty to allow everything
+}
+
+// DefaultOptions returns the options used when a user hasn't changed anything
+func DefaultOptions() Options {
+	return Options{
+		StripMarkdown:      true,
+		ASCIIPunctuation:   true,
+		CollapseWhitespace: true,
+	}
+}
+
+// Result is the normalized text and the characters that were dropped from it
+type Result struct {
+	Text    string
+	Removed []string
+}
+
+// typographic maps typographic characters to what can be typed on a plain keyboard
+var typographic = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'",
+	"‹", "'", "›", "'",
+	"“", "\"", "”", "\"", "„", "\"", "‟", "\"", "″", "\"",
+	"«", "\"", "»", "\"",
+	"‐", "-", "‑", "-", "‒", "-", "–", "-", "—", "-", "―", "-", "−", "-",
+	"…", "...",
+	"•", "-", "·", "-",
+)
+
+// invisible characters are always removed
+var invisible = strings.NewReplacer(
+	"\u00ad", "", // soft hyphen
+	"\u200b", "", // zero width space
+	"\u200c", "", // zero width non-joiner
+	"\u2060", "", // word joiner
+	"\ufeff", "", // byte order mark
+)
+
+var (
+	codeFence    = regexp.MustCompile("(?m)^[ \\t]*```.*$")
+	heading      = regexp.MustCompile(`(?m)^[ \t]{0,3}#{1,6}[ \t]+`)
+	blockquote   = regexp.MustCompile(`(?m)^[ \t]*>[ \t]?`)
+	bullet       = regexp.MustCompile(`(?m)^[ \t]*[-*+][ \t]+`)
+	rule         = regexp.MustCompile(`(?m)^[ \t]*([-*_][ \t]*){3,}$`)
+	link         = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	emphasis     = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	italic       = regexp.MustCompile(`(^|[^\w*])[*_]([^*_\s][^*_]*?)[*_]([^\w*]|$)`)
+	inlineCode   = regexp.MustCompile("`([^`]*)`")
+	whitespace   = regexp.MustCompile(`\s+`)
+	lineTrailing = regexp.MustCompile(`(?m)[ \t]+$`)
+)
+
+// Normalize cleans up generated text according to the options
+func Normalize(text string, opts Options) Result {
+	text = invisible.Replace(text)
+
+	// Non-breaking and other exotic spaces become plain spaces
+	text = strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, text)
+
+	if opts.StripMarkdown {
+		text = stripMarkdown(text)
+	}
+	if opts.ASCIIPunctuation {
+		text = typographic.Replace(text)
+	}
+
+	var removed []string
+	if opts.Allowed != "" {
+		text, removed = removeDisallowed(text, opts.Allowed)
+	}
+
+	text = lineTrailing.ReplaceAllString(text, "")
+	if opts.CollapseWhitespace {
+		text = whitespace.ReplaceAllString(text, " ")
+	}
+	text = strings.TrimSpace(text)
+
+	if opts.MaxLength > 0 {
+		text = truncate(text, opts.MaxLength)
+	}
+
+	return Result{Text: text, Removed: removed}
+}
+
+// stripMarkdown removes common markdown markup and keeps the text
+func stripMarkdown(text string) string {
+	text = codeFence.ReplaceAllString(text, "")
+	text = rule.ReplaceAllString(text, "")
+	text = heading.ReplaceAllString(text, "")
+	text = blockquote.ReplaceAllString(text, "")
+	text = bullet.ReplaceAllString(text, "")
+	text = link.ReplaceAllString(text, "$1")
+	text = emphasis.ReplaceAllString(text, "$2")
+	text = italic.ReplaceAllString(text, "$1$2$3")
+	text = inlineCode.ReplaceAllString(text, "$1")
+	return text
+}
+
+// letters spells letters without a decomposition into a base letter and
+// marks with plain letters, and umlauts the way German spells them without
+// the dots
+var letters = strings.NewReplacer(
+	"ä", "ae", "Ä", "Ae", "ö", "oe", "Ö", "Oe", "ü", "ue", "Ü", "Ue",
+	"ß", "ss", "ẞ", "SS", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE",
+	"ø", "o", "Ø", "O", "ł", "l", "Ł", "L", "đ", "d", "Đ", "D",
+	"ð", "d", "Ð", "D", "þ", "th", "Þ", "TH", "ı", "i",
+)
+
+// transliterate spells a character with plain letters: umlauts, ligatures
+// and special letters are spelled out, and other accents and marks are
+// dropped
+func transliterate(g string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(letters.Replace(norm.NFC.String(g))) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// removeDisallowed drops every character that isn't whitespace or part of
+// the allowed set, and returns the distinct characters that were dropped.
+// Characters whose transliteration is allowed are replaced instead, so
+// "café" becomes "cafe" rather than "caf".
+func removeDisallowed(text, allowed string) (string, []string) {
+	allowedSet := map[string]bool{}
+	for _, g := range scoring.Graphemes(allowed) {
+		allowedSet[g] = true
+	}
+	isAllowed := func(s string) bool {
+		for _, g := range scoring.Graphemes(s) {
+			if !allowedSet[g] {
+				return false
+			}
+		}
+		return s != ""
+	}
+
+	var b strings.Builder
+	removedSet := map[string]bool{}
+	for _, g := range scoring.Graphemes(text) {
+		if allowedSet[g] || strings.TrimSpace(g) == "" {
+			b.WriteString(g)
+			continue
+		}
+		if plain := transliterate(g); isAllowed(plain) {
+			b.WriteString(plain)
+			continue
+		}
+		removedSet[g] = true
+	}
+
+	removed := make([]string, 0, len(removedSet))
+	for g := range removedSet {
+		removed = append(removed, g)
+	}
+	sort.Strings(removed)
+	return b.String(), removed
+}
+
+// truncate shortens the text to at most maxLength characters, cutting after
+// the last complete sentence or, failing that, the last complete word. A
+// first word longer than maxLength is kept whole rather than cut in two.
+func truncate(text string, maxLength int) string {
+	graphemes := scoring.Graphemes(text)
+	if len(graphemes) <= maxLength {
+		return text
+	}
+
+	// A word or sentence ends at i when whitespace follows it, which may be
+	// the first character past the limit
+	endsAt := func(i int) bool {
+		return strings.TrimSpace(graphemes[i]) != "" && strings.TrimSpace(graphemes[i+1]) == ""
+	}
+	for i := maxLength - 1; i > 0; i-- {
+		if strings.ContainsAny(graphemes[i], ".!?") && endsAt(i) {
+			return strings.Join(graphemes[:i+1], "")
+		}
+	}
+	for i := maxLength - 1; i >= 0; i-- {
+		if endsAt(i) {
+			return strings.Join(graphemes[:i+1], "")
+		}
+	}
+	for i := maxLength; i < len(graphemes); i++ {
+		if strings.TrimSpace(graphemes[i]) == "" {
+			return strings.Join(graphemes[:i], "")
+		}
+	}
+	return text
+}
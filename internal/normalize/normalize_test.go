@@ -0,0 +1,69 @@
+package normalize
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeAllowed(t *testing.T) {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ.,"
+	tests := []struct {
+		name    string
+		text    string
+		allowed string
+		want    string
+		removed []string
+	}{
+		{"accents are dropped", "Un café à Noël.", letters, "Un cafe a Noel.", nil},
+		{"decomposed accents", "cafe\u0301", letters, "cafe", nil},
+		{"umlauts", "Grüße aus Köln.", letters, "Gruesse aus Koeln.", nil},
+		{"decomposed umlauts", "Mu\u0308ller", letters, "Mueller", nil},
+		{"special letters", "Æsir, Øresund, Łódź.", letters, "AEsir, Oresund, Lodz.", nil},
+		{"allowed accents are kept", "café", letters + "é", "café", nil},
+		{"characters without a plain spelling", "a 😀 b ω", letters, "a b", []string{"ω", "😀"}},
+		{"transliteration must be allowed", "é", "abc", "", []string{"é"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Allowed = tt.allowed
+			result := Normalize(tt.text, opts)
+			if result.Text != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.text, result.Text, tt.want)
+			}
+			if len(result.Removed) > 0 || len(tt.removed) > 0 {
+				if !slices.Equal(result.Removed, tt.removed) {
+					t.Errorf("removed = %q, want %q", result.Removed, tt.removed)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeMaxLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLength int
+		want      string
+	}{
+		{"short enough", "One. Two.", 20, "One. Two."},
+		{"cut after a sentence", "One two. Three four five.", 20, "One two."},
+		{"cut after a word", "One two three four five", 12, "One two"},
+		{"word ending at the limit", "One two three four", 13, "One two three"},
+		{"sentence ending at the limit", "One two. Three.", 8, "One two."},
+		{"period inside a word", "Pi is 3.14159 or so", 9, "Pi is"},
+		{"first word longer than the limit", "Donaudampfschiff fahrt", 6, "Donaudampfschiff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.MaxLength = tt.maxLength
+			if got := Normalize(tt.text, opts).Text; got != tt.want {
+				t.Errorf("Normalize(%q) with MaxLength %d = %q, want %q", tt.text, tt.maxLength, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package scoring
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// Difficulty rates how hard a text is to type. Capitals, digits, punctuation
+// and symbols are weighted by how often they occur, and long words add to
+// the rating.
+func Difficulty(content string) int {
+	var chars, special, letters int
+	for _, r := range content {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		chars++
+		if unicode.IsLower(r) {
+			letters++
+		} else if unicode.IsLetter(r) {
+			letters++
+			special++
+		} else {
+			special++
+		}
+	}
+	words := len(strings.Fields(content))
+	if chars == 0 || words == 0 {
+		return models.DifficultyEasy
+	}
+
+	score := 20*float64(special)/float64(chars) + float64(letters)/float64(words)
+	switch {
+	case score < 6.5:
+		return models.DifficultyEasy
+	case score < 8:
+		return models.DifficultyMedium
+	default:
+		return models.DifficultyHard
+	}
+}
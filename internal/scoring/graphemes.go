@@ -0,0 +1,38 @@
+package scoring
+
+import (
+	"unicode"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Graphemes splits text into user-perceived characters (grapheme clusters),
+// so an umlaut written with a combining mark or an emoji made of several code
+// points counts as one character, just like it is typed and displayed. The
+// text is normalized to NFC first, so "ö" matches "o" followed by U+0308.
+func Graphemes(text string) []string {
+	text = norm.NFC.String(text)
+	graphemes := make([]string, 0, len(text))
+	state := -1
+	for len(text) > 0 {
+		var cluster string
+		cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+		graphemes = append(graphemes, cluster)
+	}
+	return graphemes
+}
+
+// IsCombining reports whether s consists only of combining marks, which
+// attach to the preceding character instead of standing on their own
+func IsCombining(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.Is(unicode.M, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package scoring
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGraphemes(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"ascii", "Go!", []string{"G", "o", "!"}},
+		{"precomposed umlaut", "über", []string{"ü", "b", "e", "r"}},
+		{"decomposed umlaut", "u\u0308ber", []string{"ü", "b", "e", "r"}},
+		{"sharp s", "Straße", []string{"S", "t", "r", "a", "ß", "e"}},
+		{"combining mark without precomposed form", "q\u0301!", []string{"q\u0301", "!"}},
+		{"stacked combining marks", "a\u0301\u0323b", []string{"ạ\u0301", "b"}},
+		{"emoji", "hi 👋", []string{"h", "i", " ", "👋"}},
+		{"emoji with skin tone", "👋🏽!", []string{"👋🏽", "!"}},
+		{"zwj sequence", "a👩\u200d💻b", []string{"a", "👩\u200d💻", "b"}},
+		{"family zwj sequence", "👨\u200d👩\u200d👧\u200d👦", []string{"👨\u200d👩\u200d👧\u200d👦"}},
+		{"flag", "🇩🇪🇫🇷", []string{"🇩🇪", "🇫🇷"}},
+		{"crlf is one character", "a\r\nb", []string{"a", "\r\n", "b"}},
+		{"empty", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Graphemes(tt.text)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Graphemes(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCombining(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"\u0308", true},
+		{"\u0301\u0323", true},
+		{"u\u0308", false},
+		{"ü", false},
+		{"a", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsCombining(tt.s); got != tt.want {
+			t.Errorf("IsCombining(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
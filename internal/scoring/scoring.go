@@ -0,0 +1,359 @@
+package scoring
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// Stroke is a recorded keystroke annotated with the state of the text it was
+// applied to. Positions are recomputed from the replayed input and do not
+// rely on the position reported by the client.
+type Stroke struct {
+	models.Keystroke
+	Index    int    `json:"index"`    // position in the input the key was applied to
+	Expected string `json:"expected"` // character expected at Index, empty past the end of the text
+	Correct  bool   `json:"correct"`  // whether a typed character matched Expected
+	Length   int    `json:"length"`   // length of the input after the key, including filled in indentation
+}
+
+// Replay applies the keystrokes to an empty input buffer in order and returns
+// the annotated strokes together with the final input. Positions count
+// grapheme clusters, not bytes or code points.
+func Replay(content string, keystrokes []models.Keystroke) ([]Stroke, []string) {
+	strokes, input, _ := replay(content, keystrokes, false)
+	return strokes, input
+}
+
+// ReplayLog replays a session log with the rules of its text kind
+func ReplayLog(log models.SessionKeystrokes) ([]Stroke, []string) {
+	strokes, input, _ := replay(log.Content, log.Keystrokes, log.Kind == models.TextKindCode)
+	return strokes, input
+}
+
+// replay implements Replay. With autoIndent, a correctly typed line break is
+// followed by the indentation of the next line without any keystrokes, and a
+// backspace right after it removes the indentation together with the line
+// break. The returned flags mark the input characters that were filled in.
+func replay(content string, keystrokes []models.Keystroke, autoIndent bool) ([]Stroke, []string, []bool) {
+	expected := Graphemes(content)
+	var input []string
+	var auto []bool
+	strokes := make([]Stroke, 0, len(keystrokes))
+
+	for _, k := range keystrokes {
+		stroke := Stroke{Keystroke: k}
+
+		if k.Backspace {
+			for len(auto) > 0 && auto[len(auto)-1] {
+				input, auto = input[:len(input)-1], auto[:len(auto)-1]
+			}
+			if len(input) > 0 {
+				input, auto = input[:len(input)-1], auto[:len(auto)-1]
+			}
+			stroke.Index = len(input)
+			stroke.Length = len(input)
+			strokes = append(strokes, stroke)
+			continue
+		}
+
+		// A lone combining mark completes the previous character
+		typed := k.Key
+		if IsCombining(typed) && len(input) > 0 && !auto[len(auto)-1] {
+			typed = input[len(input)-1] + typed
+			input, auto = input[:len(input)-1], auto[:len(auto)-1]
+		}
+
+		graphemes := Graphemes(typed)
+		if len(graphemes) != 1 {
+			// Only single characters change the input
+			continue
+		}
+		typed = graphemes[0]
+
+		stroke.Index = len(input)
+		if stroke.Index < len(expected) {
+			stroke.Expected = expected[stroke.Index]
+			stroke.Correct = typed == expected[stroke.Index]
+		}
+		stroke.Key = typed
+		input, auto = append(input, typed), append(auto, false)
+
+		if autoIndent && typed == "\n" && stroke.Correct {
+			for len(input) < len(expected) && isIndent(expected[len(input)]) {
+				input, auto = append(input, expected[len(input)]), append(auto, true)
+			}
+		}
+		stroke.Length = len(input)
+		strokes = append(strokes, stroke)
+	}
+
+	return strokes, input, auto
+}
+
+// Score recomputes the result of a session that typed the whole text from
+// its keystroke log
+func Score(text models.Text, keystrokes []models.Keystroke) models.TypingResult {
+	return ScoreTest(text, keystrokes, models.Test{Mode: models.TestText})
+}
+
+// ScoreTest recomputes the result of a session from its keystroke log by the
+// rules of its test. Keys pressed after the time limit of a timed test or
+// after the first mistake of a sudden death test are ignored. Timed tests
+// measure the speed over the whole time limit.
+func ScoreTest(text models.Text, keystrokes []models.Keystroke, test models.Test) models.TypingResult {
+	if test.Mode == models.TestTime && len(keystrokes) > 0 {
+		end := keystrokes[0].Timestamp + int64(test.Param)*1000
+		for i, k := range keystrokes {
+			if k.Timestamp > end {
+				keystrokes = keystrokes[:i]
+				break
+			}
+		}
+	}
+
+	strokes, input, auto := replay(text.Content, keystrokes, text.Kind == models.TextKindCode)
+	expected := Graphemes(text.Content)
+
+	// Sudden death ends at the first wrong key
+	died := false
+	if test.Mode == models.TestSuddenDeath {
+		for _, s := range strokes {
+			if s.Backspace || s.Correct {
+				continue
+			}
+			for i, k := range keystrokes {
+				if k.Seq == s.Seq {
+					keystrokes = keystrokes[:i+1]
+					break
+				}
+			}
+			died = true
+			break
+		}
+		if died {
+			strokes, input, auto = replay(text.Content, keystrokes, text.Kind == models.TextKindCode)
+		}
+	}
+
+	result := models.TypingResult{
+		TextID:       text.ID,
+		Test:         test,
+		ErrorDetails: []models.TypingError{},
+		ErrorWords:   []string{},
+	}
+
+	// Every wrong key press counts as an error, even if it was corrected later
+	errorPositions := map[int]bool{}
+	for _, s := range strokes {
+		if s.Backspace || s.Correct || s.Expected == "" {
+			continue
+		}
+		result.Errors++
+		result.ErrorDetails = append(result.ErrorDetails, models.TypingError{
+			ExpectedChar: s.Expected,
+			TypedChar:    s.Key,
+			Position:     s.Index,
+		})
+		errorPositions[s.Index] = true
+	}
+
+	// Accuracy compares the final input with the text. Indentation that was
+	// filled in automatically doesn't count as typed.
+	typed, correct := 0, 0
+	for i, g := range input {
+		if auto[i] {
+			continue
+		}
+		typed++
+		if i < len(expected) && g == expected[i] {
+			correct++
+		}
+	}
+	if typed > 0 {
+		result.Accuracy = 100.0 * float64(correct) / float64(typed)
+	}
+	result.UncorrectedErrors = typed - correct
+
+	// Keystroke accuracy counts every character key press, so mistakes that
+	// were corrected still count against it
+	entries, correctEntries := 0, 0
+	for _, s := range strokes {
+		if s.Backspace {
+			continue
+		}
+		entries++
+		if s.Correct {
+			correctEntries++
+		}
+	}
+	if entries > 0 {
+		result.KeystrokeAccuracy = 100.0 * float64(correctEntries) / float64(entries)
+	}
+
+	// WPM over the time between the first and the last keystroke (5 chars per
+	// word). Raw WPM counts every character typed, net WPM takes off a word per
+	// minute for every uncorrected error.
+	if len(strokes) > 1 {
+		minutes := float64(strokes[len(strokes)-1].Timestamp-strokes[0].Timestamp) / 1000.0 / 60.0
+		if test.Mode == models.TestTime {
+			minutes = float64(test.Param) / 60.0
+		}
+		if minutes > 0 {
+			result.WPM = float64(typed) / 5.0 / minutes
+			result.RawWPM = float64(entries) / 5.0 / minutes
+			result.NetWPM = max(0, result.RawWPM-float64(result.UncorrectedErrors)/minutes)
+		}
+	}
+	result.Consistency = consistency(strokes)
+
+	// Timed tests run to their end when keys were still pressed in the last
+	// second; a sudden death test ends with its first mistake
+	switch test.Mode {
+	case models.TestTime:
+		result.Finished = len(strokes) > 0 &&
+			strokes[len(strokes)-1].Timestamp-strokes[0].Timestamp >= int64(test.Param-1)*1000
+	case models.TestSuddenDeath:
+		result.Finished = died || len(input) >= len(expected)
+	default:
+		result.Finished = len(input) >= len(expected)
+	}
+	result.ErrorWords = wordsAt(expected, errorPositions)
+	return result
+}
+
+// Check compares the input typed so far against the content of a text,
+// grapheme cluster by grapheme cluster
+func Check(content, input string, elapsed time.Duration) models.TypingCheck {
+	expected := Graphemes(content)
+	typed := Graphemes(input)
+	totalChars := len(expected)
+	currentPos := len(typed)
+
+	// Count errors
+	errorCount := 0
+	for i := 0; i < currentPos && i < totalChars; i++ {
+		if expected[i] != typed[i] {
+			errorCount++
+		}
+	}
+
+	// Calculate WPM (assuming 5 chars per word)
+	var wpm float64
+	if elapsed > 0 {
+		wpm = float64(currentPos) / 5.0 / elapsed.Minutes()
+	}
+
+	// Calculate accuracy
+	var accuracy float64
+	if currentPos > 0 {
+		accuracy = 100.0 * float64(currentPos-errorCount) / float64(currentPos)
+	}
+
+	// Check if the current character is correct
+	correct := true
+	if currentPos < totalChars && currentPos > 0 {
+		if typed[currentPos-1] != expected[currentPos-1] {
+			correct = false
+		}
+	}
+
+	return models.TypingCheck{
+		Correct:    correct,
+		CurrentWPM: wpm,
+		CurrentAcc: accuracy,
+		CurrentPos: currentPos,
+		TotalChars: totalChars,
+		ErrorCount: errorCount,
+	}
+}
+
+// consistency returns the coefficient of variation of the typing speed in
+// each full second of the session, in percent. Sessions shorter than two
+// seconds have no meaningful variation and return zero.
+func consistency(strokes []Stroke) float64 {
+	if len(strokes) < 2 {
+		return 0
+	}
+	seconds := int((strokes[len(strokes)-1].Timestamp - strokes[0].Timestamp) / 1000)
+	if seconds < 2 {
+		return 0
+	}
+
+	// A trailing partial second is left out
+	counts := CharsPerSecond(strokes)
+	seconds = min(seconds, len(counts))
+	counts = counts[:seconds]
+
+	var sum float64
+	for _, c := range counts {
+		sum += c
+	}
+	mean := sum / float64(seconds)
+	if mean == 0 {
+		return 0
+	}
+	var variance float64
+	for _, c := range counts {
+		variance += (c - mean) * (c - mean)
+	}
+	variance /= float64(seconds)
+	return 100 * math.Sqrt(variance) / mean
+}
+
+// CharsPerSecond counts the characters typed in each second since the first
+// stroke, up to the second of the last stroke. Backspaces aren't counted.
+// Strokes out of order count in the nearest second of that range.
+func CharsPerSecond(strokes []Stroke) []float64 {
+	if len(strokes) == 0 {
+		return nil
+	}
+	start := strokes[0].Timestamp
+	counts := make([]float64, max(strokes[len(strokes)-1].Timestamp-start, 0)/1000+1)
+	for _, s := range strokes {
+		if !s.Backspace {
+			second := min(max(s.Timestamp-start, 0)/1000, int64(len(counts)-1))
+			counts[second]++
+		}
+	}
+	return counts
+}
+
+// wordsAt returns the distinct words of the text that contain one of the positions
+func wordsAt(text []string, positions map[int]bool) []string {
+	words := []string{}
+	seen := map[string]bool{}
+
+	start := 0
+	hasError := false
+	for i := 0; i <= len(text); i++ {
+		if i == len(text) || isSpace(text[i]) {
+			word := strings.Join(text[start:i], "")
+			if hasError && word != "" && !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+			start = i + 1
+			hasError = false
+			continue
+		}
+		if positions[i] {
+			hasError = true
+		}
+	}
+
+	return words
+}
+
+// isIndent reports whether a grapheme can be part of a line's indentation
+func isIndent(g string) bool {
+	return g == " " || g == "\t"
+}
+
+// isSpace reports whether a grapheme is whitespace
+func isSpace(g string) bool {
+	return strings.TrimFunc(g, unicode.IsSpace) == ""
+}
@@ -0,0 +1,307 @@
+package scoring
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// keys turns typed keys into a keystroke log 100ms apart. "⌫" stands for
+// Backspace.
+func keys(typed ...string) []models.Keystroke {
+	keystrokes := make([]models.Keystroke, len(typed))
+	for i, key := range typed {
+		keystrokes[i] = models.Keystroke{Seq: i, Key: key, Timestamp: int64(i) * 100}
+		if key == "⌫" {
+			keystrokes[i].Key = "Backspace"
+			keystrokes[i].Backspace = true
+		}
+	}
+	return keystrokes
+}
+
+// chars splits a string into its code points, the way they are typed one key
+// at a time
+func chars(s string) []string {
+	return strings.Split(s, "")
+}
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		keys    []models.Keystroke
+		input   []string
+		correct []bool // of the strokes that aren't backspaces
+	}{
+		{
+			name:    "precomposed umlaut",
+			content: "für",
+			keys:    keys("f", "ü", "r"),
+			input:   []string{"f", "ü", "r"},
+			correct: []bool{true, true, true},
+		},
+		{
+			name:    "decomposed umlaut matches precomposed text",
+			content: "für",
+			keys:    keys("f", "u\u0308", "r"),
+			input:   []string{"f", "ü", "r"},
+			correct: []bool{true, true, true},
+		},
+		{
+			name:    "dead key sends the combining mark separately",
+			content: "für",
+			keys:    keys("f", "u", "\u0308", "r"),
+			input:   []string{"f", "ü", "r"},
+			// The bare u is wrong until the mark completes it
+			correct: []bool{true, false, true, true},
+		},
+		{
+			name:    "precomposed typing matches decomposed text",
+			content: "cafe\u0301",
+			keys:    keys("c", "a", "f", "é"),
+			input:   []string{"c", "a", "f", "é"},
+			correct: []bool{true, true, true, true},
+		},
+		{
+			name:    "base letter with a combining mark that has no precomposed form",
+			content: "q\u0301x",
+			keys:    keys("q", "\u0301", "x"),
+			input:   []string{"q\u0301", "x"},
+			correct: []bool{false, true, true},
+		},
+		{
+			name:    "zwj emoji is one character",
+			content: "a👩\u200d💻b",
+			keys:    keys("a", "👩\u200d💻", "b"),
+			input:   []string{"a", "👩\u200d💻", "b"},
+			correct: []bool{true, true, true},
+		},
+		{
+			name:    "backspace removes a whole emoji",
+			content: "👍x",
+			keys:    keys("👎", "⌫", "👍", "x"),
+			input:   []string{"👍", "x"},
+			correct: []bool{false, true, true},
+		},
+		{
+			name:    "backspace removes a whole umlaut",
+			content: "öl",
+			keys:    keys("o", "\u0308", "⌫", "ö", "l"),
+			input:   []string{"ö", "l"},
+			correct: []bool{false, true, true, true},
+		},
+		{
+			name:    "keys of several characters are ignored",
+			content: "ab",
+			keys:    keys("a", "Shift", "b"),
+			input:   []string{"a", "b"},
+			correct: []bool{true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strokes, input := Replay(tt.content, tt.keys)
+			if !slices.Equal(input, tt.input) {
+				t.Errorf("input = %q, want %q", input, tt.input)
+			}
+			var correct []bool
+			for _, s := range strokes {
+				if !s.Backspace {
+					correct = append(correct, s.Correct)
+				}
+			}
+			if !slices.Equal(correct, tt.correct) {
+				t.Errorf("correct = %v, want %v", correct, tt.correct)
+			}
+		})
+	}
+}
+
+func TestScoreTestErrorPositions(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		keys      []models.Keystroke
+		errors    []models.TypingError
+		words     []string
+		accuracy  float64
+		finished  bool
+		remaining int // uncorrected errors
+	}{
+		{
+			name:     "positions count graphemes after an umlaut",
+			content:  "Äpfel grün",
+			keys:     keys(append(chars("Äpfel gr"), "u", "⌫", "ü", "n")...),
+			errors:   []models.TypingError{{ExpectedChar: "ü", TypedChar: "u", Position: 8}},
+			words:    []string{"grün"},
+			accuracy: 100,
+			finished: true,
+		},
+		{
+			name:     "decomposed text",
+			content:  "Mu\u0308he",
+			keys:     keys("M", "ü", "x", "⌫", "h", "e"),
+			errors:   []models.TypingError{{ExpectedChar: "h", TypedChar: "x", Position: 2}},
+			words:    []string{"Mühe"},
+			accuracy: 100,
+			finished: true,
+		},
+		{
+			name:      "positions count graphemes after a zwj emoji",
+			content:   "👩\u200d💻 code",
+			keys:      keys("👩\u200d💻", " ", "c", "o", "b", "e"),
+			errors:    []models.TypingError{{ExpectedChar: "d", TypedChar: "b", Position: 4}},
+			words:     []string{"code"},
+			accuracy:  100.0 * 5 / 6,
+			finished:  true,
+			remaining: 1,
+		},
+		{
+			name:      "flag typed wrong",
+			content:   "🇫🇷 oui",
+			keys:      keys("🇩🇪", " ", "o", "u", "i"),
+			errors:    []models.TypingError{{ExpectedChar: "🇫🇷", TypedChar: "🇩🇪", Position: 0}},
+			words:     []string{"🇫🇷"},
+			accuracy:  80,
+			finished:  true,
+			remaining: 1,
+		},
+		{
+			name:      "combining mark on the wrong letter",
+			content:   "né",
+			keys:      keys("n", "a", "\u0301"),
+			errors:    []models.TypingError{{ExpectedChar: "é", TypedChar: "a", Position: 1}, {ExpectedChar: "é", TypedChar: "á", Position: 1}},
+			words:     []string{"né"},
+			accuracy:  50,
+			finished:  true,
+			remaining: 1,
+		},
+		{
+			name:     "unfinished",
+			content:  "Grüße",
+			keys:     keys("G", "r", "ü"),
+			errors:   []models.TypingError{},
+			words:    []string{},
+			accuracy: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Score(models.Text{Content: tt.content}, tt.keys)
+			if !slices.Equal(result.ErrorDetails, tt.errors) {
+				t.Errorf("errors = %+v, want %+v", result.ErrorDetails, tt.errors)
+			}
+			if result.Errors != len(tt.errors) {
+				t.Errorf("error count = %d, want %d", result.Errors, len(tt.errors))
+			}
+			if !slices.Equal(result.ErrorWords, tt.words) {
+				t.Errorf("error words = %q, want %q", result.ErrorWords, tt.words)
+			}
+			if diff := result.Accuracy - tt.accuracy; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("accuracy = %v, want %v", result.Accuracy, tt.accuracy)
+			}
+			if result.Finished != tt.finished {
+				t.Errorf("finished = %v, want %v", result.Finished, tt.finished)
+			}
+			if result.UncorrectedErrors != tt.remaining {
+				t.Errorf("uncorrected errors = %d, want %d", result.UncorrectedErrors, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestScoreTestModes(t *testing.T) {
+	content := "Über 👍"
+	typed := keys("Ü", "b", "x", "r", " ", "👍")
+
+	t.Run("sudden death stops at the first mistake", func(t *testing.T) {
+		result := ScoreTest(models.Text{Content: content}, typed, models.Test{Mode: models.TestSuddenDeath})
+		if !result.Finished || result.Errors != 1 || result.ErrorDetails[0].Position != 2 {
+			t.Errorf("result = %+v, want one error at 2 and finished", result)
+		}
+	})
+
+	t.Run("timed test ignores keys after the limit", func(t *testing.T) {
+		late := keys("Ü", "b", "e", "r", " ", "👎")
+		late[5].Timestamp = 2000
+		result := ScoreTest(models.Text{Content: content}, late, models.Test{Mode: models.TestTime, Param: 1})
+		if result.Errors != 0 || result.Accuracy != 100 {
+			t.Errorf("result = %+v, want no errors", result)
+		}
+	})
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		input   string
+		pos     int
+		total   int
+		errors  int
+		correct bool
+	}{
+		{"umlauts count as one character", "Grüße aus Köln", "Grüße", 5, 14, 0, true},
+		{"decomposed input", "Grüße", "Gru\u0308", 3, 5, 0, true},
+		{"precomposed input", "Gru\u0308ße", "Grü", 3, 5, 0, true},
+		{"missing umlaut", "Grüße", "Gru", 3, 5, 1, false},
+		{"emoji", "a👩\u200d💻b", "a👩\u200d💻", 2, 3, 0, true},
+		{"part of a zwj sequence", "a👩\u200d💻b", "a👩", 2, 3, 1, false},
+		{"combining mark without precomposed form", "q\u0301q", "q\u0301", 1, 2, 0, true},
+		{"bare base letter", "q\u0301q", "q", 1, 2, 1, false},
+		{"error before an emoji", "xy👍z", "xz👍", 3, 4, 1, true},
+		{"empty input", "ö", "", 0, 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := Check(tt.content, tt.input, time.Minute)
+			if check.CurrentPos != tt.pos || check.TotalChars != tt.total || check.ErrorCount != tt.errors || check.Correct != tt.correct {
+				t.Errorf("Check(%q, %q) = pos %d, total %d, errors %d, correct %v; want %d, %d, %d, %v",
+					tt.content, tt.input, check.CurrentPos, check.TotalChars, check.ErrorCount, check.Correct,
+					tt.pos, tt.total, tt.errors, tt.correct)
+			}
+		})
+	}
+}
+
+func TestScoreUnorderedTimestamps(t *testing.T) {
+	tests := []struct {
+		name       string
+		timestamps []int64
+	}{
+		{"later stroke before the last", []int64{1000, 5000, 3000}},
+		{"last stroke before the first", []int64{5000, 6000, 1000}},
+		{"stroke before the first", []int64{3000, 500, 4000}},
+		{"all at the same time", []int64{2000, 2000, 2000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keystrokes := keys("a", "b", "c")
+			for i, timestamp := range tt.timestamps {
+				keystrokes[i].Timestamp = timestamp
+			}
+
+			result := Score(models.Text{Content: "abc"}, keystrokes)
+			if result.Accuracy != 100 {
+				t.Errorf("accuracy = %v, want 100", result.Accuracy)
+			}
+
+			strokes, _, _ := replay("abc", keystrokes, false)
+			var total float64
+			for _, count := range CharsPerSecond(strokes) {
+				total += count
+			}
+			if total != 3 {
+				t.Errorf("CharsPerSecond counted %v characters, want 3", total)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/textgen"
+)
+
+// LeaderboardSize is the number of entries shown on a leaderboard
+const LeaderboardSize = 20
+
+// bestKeys returns the key of each personal best category a session on a
+// text counts for
+func bestKeys(text models.Text) map[string]string {
+	return map[string]string{
+		models.BestOverall: "",
+		models.BestText:    fmt.Sprint(text.ID),
+		models.BestMode:    text.Source,
+		models.BestLength:  models.LengthOf(utf8.RuneCountInString(text.Content)),
+	}
+}
+
+// newPersonalBests returns the categories in which a completed session beat
+// the user's earlier bests in the same test, in the order overall, mode,
+// length, text
+func (s *Service) newPersonalBests(session models.Session, text models.Text, result models.TypingResult) ([]models.PersonalBest, error) {
+	if !result.Finished || result.Accuracy < s.MinAccuracy {
+		return nil, nil
+	}
+
+	keys := bestKeys(text)
+	var bests []models.PersonalBest
+	for _, category := range []string{models.BestOverall, models.BestMode, models.BestLength, models.BestText} {
+		previous, err := db.GetPersonalBests(s.DB, db.BestQuery{
+			UserID:         session.UserID,
+			Category:       category,
+			Key:            keys[category],
+			Test:           session.Test,
+			MinAccuracy:    s.MinAccuracy,
+			ExcludeSession: session.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		best := models.PersonalBest{
+			Category:    category,
+			Key:         keys[category],
+			SessionID:   session.ID,
+			TextID:      text.ID,
+			WPM:         result.WPM,
+			Accuracy:    result.Accuracy,
+			CompletedAt: time.Now(),
+			Test:        session.Test,
+		}
+		if len(previous) > 0 {
+			// Ties in speed are broken by accuracy
+			p := previous[0]
+			if result.WPM < p.WPM || (result.WPM == p.WPM && result.Accuracy <= p.Accuracy) {
+				continue
+			}
+			best.PreviousWPM = p.WPM
+		}
+		bests = append(bests, best)
+	}
+	return bests, nil
+}
+
+// PersonalBests returns the user's overall best and the bests per mode and
+// per length bucket of every test, grouped by test in the order of
+// models.Tests
+func (s *Service) PersonalBests(userID int64) ([]models.PersonalBest, error) {
+	var bests []models.PersonalBest
+	for _, category := range []string{models.BestOverall, models.BestMode, models.BestLength} {
+		found, err := db.GetPersonalBests(s.DB, db.BestQuery{
+			UserID:      userID,
+			Category:    category,
+			MinAccuracy: s.MinAccuracy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		bests = append(bests, found...)
+	}
+
+	order := map[models.Test]int{}
+	for i, test := range models.Tests() {
+		order[test] = i
+	}
+	slices.SortStableFunc(bests, func(a, b models.PersonalBest) int {
+		return order[a.Test] - order[b.Test]
+	})
+	return bests, nil
+}
+
+// TextLeaderboard ranks the best session of each user on a text
+func (s *Service) TextLeaderboard(userID, textID int64) ([]models.LeaderboardEntry, error) {
+	if _, err := s.GetText(userID, textID); err != nil {
+		return nil, err
+	}
+
+	return db.GetLeaderboard(s.DB, db.LeaderboardQuery{
+		TextID:      textID,
+		MinAccuracy: s.MinAccuracy,
+		Limit:       LeaderboardSize,
+	})
+}
+
+// WeeklyChallenge returns the challenge of the ISO week containing now with
+// its leaderboard. The challenge text is generated offline the first time it
+// is asked for, from a seed derived from the week.
+func (s *Service) WeeklyChallenge(now time.Time) (models.WeeklyChallenge, error) {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day()-(int(now.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	year, week := start.ISOWeek()
+	challenge := models.WeeklyChallenge{
+		Week:     fmt.Sprintf("%d-W%02d", year, week),
+		StartsAt: start,
+		EndsAt:   start.AddDate(0, 0, 7),
+	}
+
+	text, err := db.GetWeeklyChallenge(s.DB, challenge.Week)
+	if errors.Is(err, sql.ErrNoRows) {
+		opts := textgen.DefaultOptions(int64(year*100 + week))
+		text, err = s.storeText(models.Text{
+			Content: textgen.Generate(opts),
+			Prompt:  "Weekly challenge " + challenge.Week,
+			Kind:    models.TextKindProse,
+			Source:  models.TextSourceOffline,
+		})
+		if err != nil {
+			return models.WeeklyChallenge{}, err
+		}
+		if err := db.SaveWeeklyChallenge(s.DB, challenge.Week, text.ID); err != nil {
+			return models.WeeklyChallenge{}, err
+		}
+
+		// Another request may have saved a challenge first
+		text, err = db.GetWeeklyChallenge(s.DB, challenge.Week)
+	}
+	if err != nil {
+		return models.WeeklyChallenge{}, err
+	}
+	challenge.Text = text
+
+	challenge.Leaderboard, err = db.GetLeaderboard(s.DB, db.LeaderboardQuery{
+		TextID:      text.ID,
+		Since:       challenge.StartsAt,
+		Until:       challenge.EndsAt,
+		MinAccuracy: s.MinAccuracy,
+		Limit:       LeaderboardSize,
+	})
+	if err != nil {
+		return models.WeeklyChallenge{}, err
+	}
+	return challenge, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/importer"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+)
+
+// ImportFile splits a text, Markdown, EPUB or SRT file into passages and
+// stores them as a collection of the user. An empty name uses the title of
+// the document.
+func (s *Service) ImportFile(userID int64, name, filename string, data []byte) (models.Collection, error) {
+	doc, err := importer.Parse(filename, data)
+	if err != nil {
+		return models.Collection{}, Invalid("%s", err.Error())
+	}
+
+	// Passages follow the user's maximum length, but are cut at sentence
+	// boundaries by the importer rather than truncated
+	opts := s.normalizeOptions(userID)
+	length := importer.DefaultPassageLength
+	if opts.MaxLength > 0 && opts.MaxLength < length {
+		length = opts.MaxLength
+	}
+	opts.MaxLength = 0
+	opts.StripMarkdown = false
+
+	var passages []models.Text
+	for _, passage := range importer.Split(doc.Paragraphs, length) {
+		if passage = normalizeWith(passage, opts); passage != "" {
+			passages = append(passages, models.Text{Content: passage, Difficulty: scoring.Difficulty(passage)})
+		}
+	}
+	if len(passages) == 0 {
+		return models.Collection{}, Invalid("%s contains no text that can be typed", filepath.Base(filename))
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = doc.Title
+	}
+
+	collection := models.Collection{
+		UserID:   userID,
+		Name:     name,
+		Source:   filepath.Base(filename),
+		Format:   doc.Format,
+		Passages: len(passages),
+	}
+	collection.ID, err = db.CreateCollection(s.DB, collection, passages)
+	if err != nil {
+		return models.Collection{}, err
+	}
+
+	return s.Collection(userID, collection.ID)
+}
+
+// Collections returns the collections of a user with their progress
+func (s *Service) Collections(userID int64) ([]models.Collection, error) {
+	return db.GetCollections(s.DB, userID)
+}
+
+// Collection returns a collection of the user
+func (s *Service) Collection(userID, collectionID int64) (models.Collection, error) {
+	collection, err := db.GetCollection(s.DB, userID, collectionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Collection{}, notFound("Collection")
+	}
+	return collection, err
+}
+
+// NextPassage returns the passage after the last one the user completed
+func (s *Service) NextPassage(userID, collectionID int64) (models.Text, error) {
+	collection, err := s.Collection(userID, collectionID)
+	if err != nil {
+		return models.Text{}, err
+	}
+	if collection.Completed >= collection.Passages {
+		return models.Text{}, &Error{Code: CodeNotFound, Message: "You have finished " + collection.Name}
+	}
+
+	text, err := db.GetPassage(s.DB, collectionID, collection.Completed+1)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Text{}, notFound("Passage")
+	}
+	return text, err
+}
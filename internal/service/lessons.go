@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/curriculum"
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/wordlist"
+)
+
+// lessonWords is the length of a lesson text in words
+const lessonWords = 30
+
+// minLessonWords is the least number of usable words an LLM text of a lesson
+// needs, after the words with keys that aren't unlocked are dropped
+const minLessonWords = 15
+
+// LessonStatus is a lesson of a user's curriculum with the user's progress.
+// A lesson is unlocked once the lesson before it is passed.
+type LessonStatus struct {
+	curriculum.Lesson
+	Progress models.LessonProgress `json:"progress"`
+	Unlocked bool                  `json:"unlocked"`
+}
+
+// Curriculum returns the lessons for the user's keyboard layout with the
+// user's progress
+func (s *Service) Curriculum(userID int64) ([]LessonStatus, error) {
+	layout, err := s.Layout(userID)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := db.GetLessonProgress(s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []LessonStatus
+	unlocked := true
+	for _, lesson := range curriculum.Lessons(layout) {
+		p, ok := progress[lesson.ID]
+		if !ok {
+			p = models.LessonProgress{Lesson: lesson.ID}
+		}
+		statuses = append(statuses, LessonStatus{Lesson: lesson, Progress: p, Unlocked: unlocked})
+		unlocked = p.Passed()
+	}
+	return statuses, nil
+}
+
+// NextLesson returns the first lesson of the user's curriculum the user
+// hasn't passed, or the last lesson once all are passed
+func NextLesson(statuses []LessonStatus) LessonStatus {
+	for _, status := range statuses {
+		if !status.Progress.Passed() {
+			return status
+		}
+	}
+	return statuses[len(statuses)-1]
+}
+
+// GenerateLesson generates a text for a lesson of the user's curriculum that
+// only uses the keys unlocked up to the lesson. An empty ID picks the next
+// lesson. The LLM is used unless offline is set or no remote provider is
+// configured; it falls back to the word list when its text has too few
+// words that can be typed with the unlocked keys.
+func (s *Service) GenerateLesson(ctx context.Context, userID int64, lessonID string, offline bool) (models.Text, error) {
+	statuses, err := s.Curriculum(userID)
+	if err != nil {
+		return models.Text{}, err
+	}
+
+	status := NextLesson(statuses)
+	if lessonID != "" {
+		found := false
+		for _, st := range statuses {
+			if st.ID == lessonID {
+				status, found = st, true
+			}
+		}
+		if !found {
+			return models.Text{}, notFound("Lesson")
+		}
+	}
+	if !status.Unlocked {
+		return models.Text{}, &Error{Code: CodeConflict, Message: fmt.Sprintf("Pass lesson %d first", status.Number-1)}
+	}
+	lesson := status.Lesson
+
+	var content string
+	offline = offline || s.Generator.Name() == "fallback"
+	if !offline {
+		content, err = s.Generator.GenerateText(ctx, curriculum.Prompt(lesson))
+		if err != nil {
+			fmt.Printf("Lesson generation failed, using word list: %v\n", err)
+		} else {
+			content = lesson.Filter(s.normalizeText(userID, content))
+		}
+		offline = err != nil || len(strings.Fields(content)) < minLessonWords
+	}
+	if offline {
+		content = curriculum.Generate(lesson, wordlist.Top(5000), lessonWords, time.Now().UnixNano())
+	}
+
+	return s.storeText(models.Text{
+		Content: content,
+		Prompt:  fmt.Sprintf("Lesson %d: %s", lesson.Number, lesson.Name),
+		Kind:    models.TextKindProse,
+		Source:  models.TextSourceLesson,
+		Lesson:  lesson.ID,
+	})
+}
+
+// recordLesson counts a finished session of a lesson text toward the user's
+// progress through the lesson
+func (s *Service) recordLesson(userID int64, text models.Text, result models.TypingResult) (*models.LessonResult, error) {
+	lesson, ok := curriculum.Find(text.Lesson)
+	if !ok {
+		return nil, fmt.Errorf("unknown lesson %q", text.Lesson)
+	}
+
+	passed := lesson.Passes(result.WPM, result.Accuracy)
+	progress, err := db.RecordLessonAttempt(s.DB, userID, lesson.ID, result.WPM, result.Accuracy, passed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LessonResult{
+		Name:           lesson.Name,
+		Number:         lesson.Number,
+		TargetWPM:      lesson.TargetWPM,
+		TargetAccuracy: lesson.TargetAccuracy,
+		Passed:         passed,
+		Progress:       progress,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// Limits of tags
+const (
+	MaxTags      = 10
+	MaxTagLength = 32
+)
+
+// LibraryQuery selects texts of the library. Empty fields don't filter.
+type LibraryQuery struct {
+	Query        string
+	Length       string // a name in models.TextLengths
+	Difficulty   string // a name in models.DifficultyNames
+	Source       string // one of models.TextSources
+	Tag          string
+	CollectionID int64
+	Limit        int
+	Offset       int
+}
+
+// Library searches the texts the user can type again
+func (s *Service) Library(userID int64, q LibraryQuery) ([]models.LibraryText, error) {
+	filter := db.LibraryFilter{
+		UserID:       userID,
+		Query:        q.Query,
+		FullText:     s.FullText,
+		Tag:          normalizeTag(q.Tag),
+		CollectionID: q.CollectionID,
+	}
+	filter.Limit, filter.Offset = page(q.Limit, q.Offset)
+
+	if q.Length != "" {
+		i := slices.IndexFunc(models.TextLengths, func(r models.LengthRange) bool { return r.Name == q.Length })
+		if i < 0 {
+			return nil, Invalid("Unknown length %q, use short, medium or long", q.Length)
+		}
+		filter.MinLength, filter.MaxLength = models.TextLengths[i].Min, models.TextLengths[i].Max
+	}
+	if q.Difficulty != "" {
+		filter.Difficulty = slices.Index(models.DifficultyNames, q.Difficulty)
+		if filter.Difficulty <= 0 {
+			return nil, Invalid("Unknown difficulty %q, use easy, medium or hard", q.Difficulty)
+		}
+	}
+	if q.Source != "" {
+		if !slices.Contains(models.TextSources, q.Source) {
+			return nil, Invalid("Unknown source %q", q.Source)
+		}
+		filter.Source = q.Source
+	}
+
+	return db.SearchLibrary(s.DB, filter)
+}
+
+// TagText replaces the tags the user gave a text and returns the tags as
+// they were stored. Tags are lowercased, and commas separate tags.
+func (s *Service) TagText(userID, textID int64, tags []string) ([]string, error) {
+	if _, err := s.GetText(userID, textID); err != nil {
+		return nil, err
+	}
+
+	normalized := []string{}
+	for _, tag := range tags {
+		for _, name := range strings.Split(tag, ",") {
+			name = normalizeTag(name)
+			if name == "" || slices.Contains(normalized, name) {
+				continue
+			}
+			if utf8.RuneCountInString(name) > MaxTagLength {
+				return nil, Invalid("Tags can be at most %d characters long", MaxTagLength)
+			}
+			normalized = append(normalized, name)
+		}
+	}
+	if len(normalized) > MaxTags {
+		return nil, Invalid("A text can have at most %d tags", MaxTags)
+	}
+	slices.Sort(normalized)
+
+	if err := db.SetTextTags(s.DB, userID, textID, normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// Tags returns the names of the user's tags
+func (s *Service) Tags(userID int64) ([]string, error) {
+	return db.GetTags(s.DB, userID)
+}
+
+// normalizeTag lowercases a tag and collapses its whitespace
+func normalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}
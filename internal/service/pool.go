@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/llm"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/textgen"
+)
+
+// PoolCategory is a kind of text the pool keeps ready for a generic prompt
+type PoolCategory struct {
+	Name   string
+	Prompt string
+}
+
+// PoolCategories are the categories of the text pool. The first one serves
+// the default prompt.
+var PoolCategories = []PoolCategory{
+	{Name: "general", Prompt: DefaultPrompt},
+	{Name: "nature", Prompt: "Give me a typing practice text about nature"},
+	{Name: "science", Prompt: "Give me a typing practice text about science"},
+	{Name: "history", Prompt: "Give me a typing practice text about history"},
+	{Name: "technology", Prompt: "Give me a typing practice text about technology"},
+}
+
+// PoolBucket holds the pooled texts of a category generated for a
+// difficulty level
+type PoolBucket struct {
+	Category   string
+	Difficulty int
+}
+
+// PoolBuckets lists every bucket of the pool, default prompt first
+func PoolBuckets() []PoolBucket {
+	var buckets []PoolBucket
+	for _, category := range PoolCategories {
+		for level := models.DifficultyEasy; level <= models.DifficultyHard; level++ {
+			buckets = append(buckets, PoolBucket{Category: category.Name, Difficulty: level})
+		}
+	}
+	return buckets
+}
+
+// PoolConfig controls how the pool is refilled
+type PoolConfig struct {
+	Size      int           // texts kept per bucket, 0 turns the pool off
+	Threshold int           // a bucket is refilled once it holds fewer texts
+	Interval  time.Duration // the least time between two generations
+}
+
+// DefaultPoolConfig returns the pool configuration used unless the server
+// is configured otherwise
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{Size: 3, Threshold: 2, Interval: 10 * time.Second}
+}
+
+// difficultyHints ask the LLM for a difficulty level, indexed by level
+var difficultyHints = []string{
+	"",
+	"Make it easy to type: short, common words, few capital letters and little punctuation.",
+	"Make it moderately hard to type: everyday words with some capital letters and punctuation.",
+	"Make it hard to type: longer and less common words, numbers and varied punctuation.",
+}
+
+// difficultyPrompt adds the hint of a difficulty level to a prompt. Level 0
+// leaves the prompt as it is.
+func difficultyPrompt(prompt string, level int) string {
+	if level == 0 {
+		return prompt
+	}
+	return prompt + ". " + difficultyHints[level]
+}
+
+// parseDifficulty reads a difficulty level from its name. An empty name is
+// level 0, any difficulty.
+func parseDifficulty(name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+	level := slices.Index(models.DifficultyNames, name)
+	if level < 1 {
+		return 0, Invalid("Unknown difficulty %q", name)
+	}
+	return level, nil
+}
+
+// poolCategory returns the category of a generic prompt: no prompt at all,
+// the prompt of a category or the name of one
+func poolCategory(prompt string) (PoolCategory, bool) {
+	prompt = strings.TrimRight(strings.ToLower(strings.TrimSpace(prompt)), ".!")
+	if prompt == "" {
+		return PoolCategories[0], true
+	}
+	for _, category := range PoolCategories {
+		if prompt == category.Name || prompt == strings.ToLower(category.Prompt) {
+			return category, true
+		}
+	}
+	return PoolCategory{}, false
+}
+
+// PooledText serves a text for a generic prompt from the pool and reports
+// whether it did. Other prompts and empty buckets are left to the LLM,
+// except for the default prompt, which never waits on the network: without
+// a pooled text it gets one of the offline generator.
+func (s *Service) PooledText(userID int64, prompt, difficulty string) (models.Text, bool, error) {
+	category, ok := poolCategory(prompt)
+	if !ok {
+		return models.Text{}, false, nil
+	}
+	level, err := parseDifficulty(difficulty)
+	if err != nil {
+		return models.Text{}, false, err
+	}
+
+	content, source, err := db.TakePoolText(s.DB, category.Name, level)
+	if errors.Is(err, sql.ErrNoRows) {
+		if category.Name != PoolCategories[0].Name {
+			return models.Text{}, false, nil
+		}
+		text, err := s.GenerateOffline(offlineDifficulty(level, time.Now().UnixNano()))
+		return text, err == nil, err
+	}
+	if err != nil {
+		return models.Text{}, false, err
+	}
+
+	// Generate a text of its own when nothing of the pooled one is allowed
+	content = s.normalizeText(userID, content)
+	if content == "" {
+		return models.Text{}, false, nil
+	}
+
+	text, err := s.saveText(content, category.Prompt, source)
+	return text, err == nil, err
+}
+
+// offlineDifficulty returns offline generator options for a difficulty level
+func offlineDifficulty(level int, seed int64) textgen.Options {
+	opts := textgen.DefaultOptions(seed)
+	switch level {
+	case models.DifficultyEasy:
+		opts.Rank = 200
+		opts.Punctuation = 0.1
+	case models.DifficultyHard:
+		opts.Rank = 5000
+		opts.Punctuation = 1
+		opts.Numbers = true
+	}
+	return opts
+}
+
+// PoolCounts returns how many texts every bucket of the pool holds
+func (s *Service) PoolCounts() (map[PoolBucket]int, error) {
+	counts, err := db.CountPoolTexts(s.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[PoolBucket]int{}
+	for category, levels := range counts {
+		for level, count := range levels {
+			result[PoolBucket{Category: category, Difficulty: level}] = count
+		}
+	}
+	return result, nil
+}
+
+// RefillPool generates a text for a bucket of the pool. The text is stored
+// as generated and normalized for the user it is served to. Texts of the
+// fallback provider are served as offline texts.
+func (s *Service) RefillPool(ctx context.Context, bucket PoolBucket) error {
+	i := slices.IndexFunc(PoolCategories, func(c PoolCategory) bool { return c.Name == bucket.Category })
+	if i < 0 {
+		return Invalid("Unknown pool category %q", bucket.Category)
+	}
+	prompt := difficultyPrompt(PoolCategories[i].Prompt, bucket.Difficulty)
+
+	// Streaming lets the shutdown cancel a generation in progress
+	content, err := llm.StreamText(ctx, s.Generator, prompt, func(string) error { return nil })
+	if err != nil {
+		return fmt.Errorf("generating %s text: %w", bucket.Category, err)
+	}
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("generating %s text: empty text", bucket.Category)
+	}
+
+	source := models.TextSourceLLM
+	if s.Generator.Name() == "fallback" {
+		source = models.TextSourceOffline
+	}
+	return db.AddPoolText(s.DB, bucket.Category, bucket.Difficulty, content, source)
+}
@@ -0,0 +1,95 @@
+// Package service implements Figure10's operations independently of how they
+// are served. Both the HTML handlers and the JSON API are built on top of it.
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/janislaus/figure10/internal/llm"
+	"github.com/janislaus/figure10/internal/snippets"
+)
+
+// Error codes of errors that are the caller's fault
+const (
+	CodeInvalid      = "invalid_request"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeUnauthorized = "unauthorized"
+)
+
+// Error is an error caused by the request rather than by the server. Any
+// other error returned by the service is an internal failure.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Invalid returns an Error for a malformed request
+func Invalid(format string, args ...interface{}) error {
+	return &Error{Code: CodeInvalid, Message: fmt.Sprintf(format, args...)}
+}
+
+// notFound returns an Error for a missing resource
+func notFound(what string) error {
+	return &Error{Code: CodeNotFound, Message: what + " not found"}
+}
+
+// AsError returns the Error wrapped in err, if any
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// StatusCode returns the HTTP status code matching an error
+func StatusCode(err error) int {
+	e, ok := AsError(err)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+
+	switch e.Code {
+	case CodeInvalid:
+		return http.StatusBadRequest
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Service holds the dependencies of the service operations
+type Service struct {
+	DB        *sql.DB
+	Generator llm.Provider
+	Code      *snippets.Source // nil when code mode isn't configured
+	FullText  bool             // whether the FTS5 index of texts is available
+
+	// MinAccuracy is the accuracy in percent a session needs to count for
+	// personal bests and leaderboards
+	MinAccuracy float64
+}
+
+// DefaultMinAccuracy is the default accuracy floor of personal bests and
+// leaderboards
+const DefaultMinAccuracy = 90.0
+
+// New creates a new Service with the given dependencies
+func New(db *sql.DB, generator llm.Provider) *Service {
+	return &Service{
+		DB:          db,
+		Generator:   generator,
+		MinAccuracy: DefaultMinAccuracy,
+	}
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/janislaus/figure10/internal/analytics"
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+)
+
+// StartSession starts a typing session of a user on a text. A test without
+// a mode types the whole text. Timed and sudden death tests need a text of
+// StartTest, as theirs grows while it is typed.
+func (s *Service) StartSession(userID, textID int64, test models.Test) (models.Session, models.Text, error) {
+	test, err := checkTest(test)
+	if err != nil {
+		return models.Session{}, models.Text{}, err
+	}
+
+	text, err := s.GetText(userID, textID)
+	if err != nil {
+		return models.Session{}, models.Text{}, err
+	}
+	if test.Streamed() && !text.Streamed {
+		return models.Session{}, models.Text{}, Invalid("Timed and sudden death tests run on a text started with the test")
+	}
+
+	settings, err := db.GetUserSettings(s.DB, userID)
+	if err != nil {
+		return models.Session{}, models.Text{}, err
+	}
+
+	sessionID, err := db.StartSession(s.DB, userID, text.ID, test, settings.Layout)
+	if err != nil {
+		return models.Session{}, models.Text{}, err
+	}
+
+	session := models.Session{ID: sessionID, UserID: userID, TextID: text.ID, Test: test}
+	return session, text, nil
+}
+
+// RecordKeystrokes appends a batch of raw keystroke events to a session that
+// is in progress. Timestamps must not go back in time, counting the events
+// recorded before.
+func (s *Service) RecordKeystrokes(userID, sessionID int64, events []models.Keystroke) error {
+	session, err := s.openSession(userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	recorded, err := db.GetKeystrokes(s.DB, session.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkTimestamps(recorded, events); err != nil {
+		return err
+	}
+
+	return db.SaveKeystrokes(s.DB, session.ID, events)
+}
+
+// checkTimestamps checks that the timestamps of the recorded and new events
+// are positive and ordered by sequence number. New events with the sequence
+// number of a recorded one are ignored when they are saved.
+func checkTimestamps(recorded, events []models.Keystroke) error {
+	timestamps := map[int]int64{}
+	for _, k := range recorded {
+		timestamps[k.Seq] = k.Timestamp
+	}
+	for _, k := range events {
+		if k.Timestamp < 0 {
+			return Invalid("Keystroke %d has a negative timestamp", k.Seq)
+		}
+		if _, ok := timestamps[k.Seq]; !ok {
+			timestamps[k.Seq] = k.Timestamp
+		}
+	}
+
+	seqs := slices.Sorted(maps.Keys(timestamps))
+	for i := 1; i < len(seqs); i++ {
+		if timestamps[seqs[i]] < timestamps[seqs[i-1]] {
+			return Invalid("Keystroke %d has an earlier timestamp than keystroke %d", seqs[i], seqs[i-1])
+		}
+	}
+	return nil
+}
+
+// SubmitSession scores a session from its recorded keystrokes and completes it
+func (s *Service) SubmitSession(userID, sessionID int64) (models.TypingResult, error) {
+	session, err := s.openSession(userID, sessionID)
+	if err != nil {
+		return models.TypingResult{}, err
+	}
+
+	text, err := db.GetTextByID(s.DB, session.TextID)
+	if err != nil {
+		return models.TypingResult{}, err
+	}
+
+	keystrokes, err := db.GetKeystrokes(s.DB, session.ID)
+	if err != nil {
+		return models.TypingResult{}, err
+	}
+
+	// Score the session from the keystroke log by the rules of its test
+	result := scoring.ScoreTest(text, keystrokes, session.Test)
+	result.SessionID = session.ID
+
+	// Save the session results to the database
+	err = db.CompleteSession(s.DB, session.ID, result)
+	if err != nil {
+		return models.TypingResult{}, err
+	}
+
+	// Save the error details
+	for _, e := range result.ErrorDetails {
+		err := db.SaveTypingError(s.DB, session.UserID, session.ID, e.ExpectedChar, e.TypedChar, e.Position)
+		if err != nil {
+			// Log the error but continue
+			fmt.Printf("Failed to save typing error: %v\n", err)
+		}
+	}
+
+	// Compare the result with the user's earlier bests
+	result.PersonalBests, err = s.newPersonalBests(session, text, result)
+	if err != nil {
+		// Log the error but continue
+		fmt.Printf("Failed to check personal bests: %v\n", err)
+	}
+	if result.PersonalBests == nil {
+		result.PersonalBests = []models.PersonalBest{}
+	}
+
+	// Count the session toward the lesson of a lesson text
+	if text.Lesson != "" && result.Finished {
+		result.Lesson, err = s.recordLesson(session.UserID, text, result)
+		if err != nil {
+			// Log the error but continue
+			fmt.Printf("Failed to record lesson progress: %v\n", err)
+		}
+	}
+
+	return result, nil
+}
+
+// GetSession returns a session of a user with its text and errors
+func (s *Service) GetSession(userID, sessionID int64) (models.SessionDetail, error) {
+	session, err := s.session(userID, sessionID)
+	if err != nil {
+		return models.SessionDetail{}, err
+	}
+
+	text, err := db.GetTextByID(s.DB, session.TextID)
+	if err != nil {
+		return models.SessionDetail{}, err
+	}
+
+	typingErrors, err := db.GetSessionErrors(s.DB, userID, session.ID)
+	if err != nil {
+		return models.SessionDetail{}, err
+	}
+
+	return models.SessionDetail{Session: session, Text: text, Errors: typingErrors}, nil
+}
+
+// SessionBreakdown is a session with its text and errors, broken down word
+// by word, with the pauses of its keystroke log and the user's other
+// sessions of the same test on the text to compare it with
+type SessionBreakdown struct {
+	models.SessionDetail
+	Words   []analytics.WordStat `json:"words"`
+	Pauses  []analytics.Pause    `json:"pauses"`
+	Average models.TextStats     `json:"average"`
+}
+
+// Breakdown analyzes a session of a user word by word. Sessions completed
+// without a keystroke log have their words untyped and no pauses.
+func (s *Service) Breakdown(userID, sessionID int64) (SessionBreakdown, error) {
+	detail, err := s.GetSession(userID, sessionID)
+	if err != nil {
+		return SessionBreakdown{}, err
+	}
+	if detail.Errors == nil {
+		detail.Errors = []models.TypingError{}
+	}
+
+	log, err := s.sessionLog(detail.Session, detail.Text)
+	if err != nil {
+		return SessionBreakdown{}, err
+	}
+
+	average, err := db.GetTextStats(s.DB, userID, detail.Text.ID, detail.Session.Test, detail.Session.ID)
+	if err != nil {
+		return SessionBreakdown{}, err
+	}
+
+	return SessionBreakdown{
+		SessionDetail: detail,
+		Words:         analytics.Words(log),
+		Pauses:        analytics.NewReplay(log).Pauses,
+		Average:       average,
+	}, nil
+}
+
+// ListSessions returns the most recent completed sessions of a user
+func (s *Service) ListSessions(userID int64, limit int) ([]models.SessionWithText, error) {
+	limit, _ = page(limit, 0)
+	return db.GetRecentSessions(s.DB, userID, limit)
+}
+
+// CommonErrors returns the most common typing errors of a user
+func (s *Service) CommonErrors(userID int64, limit int) ([]models.CommonError, error) {
+	limit, _ = page(limit, 0)
+	return db.GetCommonErrors(s.DB, userID, limit)
+}
+
+// Stats summarizes the completed sessions of a user
+func (s *Service) Stats(userID int64) (models.Stats, error) {
+	return db.GetStats(s.DB, userID)
+}
+
+// LatencyReport builds the keystroke latency analytics of a user
+func (s *Service) LatencyReport(userID int64, sortBy string) (analytics.Report, error) {
+	logs, err := db.GetKeystrokeLogs(s.DB, userID, 200)
+	if err != nil {
+		return analytics.Report{}, err
+	}
+
+	typingErrors, err := db.GetLayoutErrors(s.DB, userID)
+	if err != nil {
+		return analytics.Report{}, err
+	}
+
+	return analytics.NewReport(logs, typingErrors, sortBy), nil
+}
+
+// KeyErrors computes the error rate of each key the user typed, named by
+// the character it types without modifiers
+func (s *Service) KeyErrors(userID int64) ([]analytics.ErrorRate, error) {
+	logs, err := db.GetKeystrokeLogs(s.DB, userID, 200)
+	if err != nil {
+		return nil, err
+	}
+	return analytics.KeyErrorRates(logs), nil
+}
+
+// SessionReplay is a completed session with its text and its keystroke log
+// prepared for playback
+type SessionReplay struct {
+	Session models.Session `json:"session"`
+	Text    models.Text    `json:"text"`
+	analytics.Replay
+}
+
+// Replay prepares the keystroke log of a completed session of a user for
+// playback. Sessions completed without a keystroke log have no strokes.
+func (s *Service) Replay(userID, sessionID int64) (SessionReplay, error) {
+	session, err := s.session(userID, sessionID)
+	if err != nil {
+		return SessionReplay{}, err
+	}
+	if session.CompletedAt.IsZero() {
+		return SessionReplay{}, &Error{Code: CodeConflict, Message: "Session not completed yet"}
+	}
+
+	text, err := s.GetText(userID, session.TextID)
+	if err != nil {
+		return SessionReplay{}, err
+	}
+	log, err := s.sessionLog(session, text)
+	if err != nil {
+		return SessionReplay{}, err
+	}
+
+	return SessionReplay{Session: session, Text: text, Replay: analytics.NewReplay(log)}, nil
+}
+
+// sessionLog loads the keystroke log of a session typed on a text
+func (s *Service) sessionLog(session models.Session, text models.Text) (models.SessionKeystrokes, error) {
+	keystrokes, err := db.GetKeystrokes(s.DB, session.ID)
+	if err != nil {
+		return models.SessionKeystrokes{}, err
+	}
+
+	return models.SessionKeystrokes{
+		SessionID:   session.ID,
+		Content:     text.Content,
+		Kind:        text.Kind,
+		CompletedAt: session.CompletedAt,
+		Keystrokes:  keystrokes,
+	}, nil
+}
+
+// session loads a session of a user
+func (s *Service) session(userID, sessionID int64) (models.Session, error) {
+	session, err := db.GetSessionByID(s.DB, userID, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Session{}, notFound("Session")
+	}
+	return session, err
+}
+
+// openSession loads a session of a user that is still in progress
+func (s *Service) openSession(userID, sessionID int64) (models.Session, error) {
+	session, err := s.session(userID, sessionID)
+	if err != nil {
+		return models.Session{}, err
+	}
+	if !session.CompletedAt.IsZero() {
+		return models.Session{}, &Error{Code: CodeConflict, Message: "Session already completed"}
+	}
+	return session, nil
+}
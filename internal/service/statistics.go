@@ -0,0 +1,93 @@
+package service
+
+import (
+	"slices"
+	"time"
+
+	"github.com/janislaus/figure10/internal/analytics"
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/models"
+)
+
+// DateLayout is the format of dates in queries
+const DateLayout = "2006-01-02"
+
+// Limits of the progress statistics
+const (
+	DefaultProgressDays  = 30
+	DefaultProgressWeeks = 12
+	MaxProgressBuckets   = 366
+)
+
+// ProgressQuery selects the progress statistics of a date range. Dates are
+// given in DateLayout and are UTC; an empty To is today and an empty From
+// covers the default number of days or weeks. An empty mode includes every
+// text source and an empty test every test.
+type ProgressQuery struct {
+	Period string // analytics.PeriodDay or analytics.PeriodWeek
+	From   string
+	To     string
+	Mode   string
+	Test   string // the key of a test, e.g. "time:60"
+}
+
+// Progress computes the daily or weekly progress statistics of a user
+func (s *Service) Progress(userID int64, q ProgressQuery, now time.Time) (analytics.ProgressReport, error) {
+	period := q.Period
+	if period == "" {
+		period = analytics.PeriodDay
+	}
+	if _, ok := analytics.RollingWindow[period]; !ok {
+		return analytics.ProgressReport{}, Invalid("Unknown period %q, use day or week", period)
+	}
+	if q.Mode != "" && !slices.Contains(models.TextSources, q.Mode) {
+		return analytics.ProgressReport{}, Invalid("Unknown mode %q", q.Mode)
+	}
+	var test models.Test
+	if q.Test != "" {
+		var err error
+		if test, err = ParseTest(q.Test); err != nil {
+			return analytics.ProgressReport{}, err
+		}
+	}
+
+	now = now.UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if q.To != "" {
+		parsed, err := time.Parse(DateLayout, q.To)
+		if err != nil {
+			return analytics.ProgressReport{}, Invalid("Invalid date %q, use YYYY-MM-DD", q.To)
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, 1-DefaultProgressDays)
+	if period == analytics.PeriodWeek {
+		from = to.AddDate(0, 0, 1-7*DefaultProgressWeeks)
+	}
+	if q.From != "" {
+		parsed, err := time.Parse(DateLayout, q.From)
+		if err != nil {
+			return analytics.ProgressReport{}, Invalid("Invalid date %q, use YYYY-MM-DD", q.From)
+		}
+		from = parsed
+	}
+
+	// Buckets always cover whole periods
+	from = analytics.PeriodStart(from, period)
+	if from.After(to) {
+		return analytics.ProgressReport{}, Invalid("The start date must not be after the end date")
+	}
+	if analytics.ShiftPeriod(from, period, MaxProgressBuckets).Before(to) {
+		return analytics.ProgressReport{}, Invalid("The date range can span at most %d %ss", MaxProgressBuckets, period)
+	}
+
+	// Load the periods before the range too, for the rolling averages
+	since := analytics.ShiftPeriod(from, period, 1-analytics.RollingWindow[period])
+	samples, err := db.GetSessionSamples(s.DB, userID, since, to.AddDate(0, 0, 1), q.Mode, test)
+	if err != nil {
+		return analytics.ProgressReport{}, err
+	}
+
+	return analytics.Progress(samples, period, from, to), nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/scoring"
+	"github.com/janislaus/figure10/internal/textgen"
+)
+
+// Streamed tests start with a chunk of words and are extended by another
+// chunk whenever the typist gets close to the end, up to a limit
+const (
+	StreamChunkWords = 50
+	MaxStreamWords   = 2000
+)
+
+// ParseTest reads a test from its key, e.g. "time:60". An empty key is the
+// full text test.
+func ParseTest(key string) (models.Test, error) {
+	mode, param, found := strings.Cut(key, ":")
+	test := models.Test{Mode: mode}
+	if found {
+		value, err := strconv.Atoi(param)
+		if err != nil {
+			return models.Test{}, Invalid("Unknown test %q", key)
+		}
+		test.Param = value
+	}
+	return checkTest(test)
+}
+
+// checkTest validates a test. A test without a mode is the full text test.
+func checkTest(test models.Test) (models.Test, error) {
+	if test.Mode == "" && test.Param == 0 {
+		test.Mode = models.TestText
+	}
+	switch test.Mode {
+	case models.TestTime:
+		if !slices.Contains(models.TestDurations, test.Param) {
+			return models.Test{}, Invalid("Timed tests last one of %v seconds", models.TestDurations)
+		}
+	case models.TestWords:
+		if !slices.Contains(models.TestWordCounts, test.Param) {
+			return models.Test{}, Invalid("Word count tests have one of %v words", models.TestWordCounts)
+		}
+	case models.TestText, models.TestSuddenDeath:
+		if test.Param != 0 {
+			return models.Test{}, Invalid("The %s test takes no parameter", test.Mode)
+		}
+	default:
+		return models.Test{}, Invalid("Unknown test mode %q", test.Mode)
+	}
+	return test, nil
+}
+
+// StartTest generates the text a test is typed on with the offline
+// generator: as many words as a word count test asks for, the first chunk of
+// the stream of a timed or sudden death test, and a default text otherwise.
+// Streams are marked, as they are the only texts ExtendText grows.
+func (s *Service) StartTest(test models.Test) (models.Text, error) {
+	test, err := checkTest(test)
+	if err != nil {
+		return models.Text{}, err
+	}
+
+	opts := textgen.DefaultOptions(time.Now().UnixNano())
+	if test.Mode == models.TestWords {
+		opts.Words = test.Param
+	} else if test.Streamed() {
+		opts.Words = StreamChunkWords
+	}
+
+	return s.storeText(models.Text{
+		Content:  textgen.Generate(opts),
+		Prompt:   "Test: " + test.Name(),
+		Kind:     models.TextKindProse,
+		Source:   models.TextSourceOffline,
+		Streamed: test.Streamed(),
+	})
+}
+
+// ExtendText appends the next chunk of the stream to the text of a timed or
+// sudden death session in progress and returns the appended content. Only
+// texts of StartTest grow. A text other sessions were typed on stays as it
+// is: the session moves on to a grown copy of it instead.
+func (s *Service) ExtendText(userID, sessionID int64) (string, error) {
+	session, err := s.openSession(userID, sessionID)
+	if err != nil {
+		return "", err
+	}
+	if !session.Test.Streamed() {
+		return "", Invalid("Only timed and sudden death tests stream text")
+	}
+
+	text, err := db.GetTextByID(s.DB, session.TextID)
+	if err != nil {
+		return "", err
+	}
+	if !text.Streamed {
+		return "", Invalid("Only texts started as a timed or sudden death test can grow")
+	}
+	if len(strings.Fields(text.Content)) >= MaxStreamWords {
+		return "", Invalid("The text can't grow beyond %d words", MaxStreamWords)
+	}
+
+	opts := textgen.DefaultOptions(time.Now().UnixNano())
+	opts.Words = StreamChunkWords
+	more := " " + textgen.Generate(opts)
+
+	difficulty := scoring.Difficulty(text.Content + more)
+
+	sessions, err := db.CountTextSessions(s.DB, text.ID)
+	if err != nil {
+		return "", err
+	}
+	if sessions > 1 {
+		_, err = db.ForkText(s.DB, session.ID, text, more, difficulty)
+	} else {
+		err = db.AppendText(s.DB, text.ID, more, difficulty)
+	}
+	if err != nil {
+		return "", err
+	}
+	return more, nil
+}
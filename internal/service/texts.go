@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/analytics"
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/drill"
+	"github.com/janislaus/figure10/internal/llm"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/normalize"
+	"github.com/janislaus/figure10/internal/scoring"
+	"github.com/janislaus/figure10/internal/snippets"
+	"github.com/janislaus/figure10/internal/textgen"
+	"github.com/janislaus/figure10/internal/wordlist"
+)
+
+// DefaultPrompt is used when text is generated without a prompt
+const DefaultPrompt = "Give me a general typing practice text"
+
+// Page sizes of list operations
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// errEmptyText is returned for generated text that has nothing left to type
+// after normalization, e.g. when no character of it is allowed
+var errEmptyText = errors.New("the provider returned no text that can be typed")
+
+// GenerateText generates a text from a prompt with the configured provider,
+// asking for a difficulty level by name, or any difficulty when it is
+// empty. Generic prompts are served from the text pool.
+func (s *Service) GenerateText(ctx context.Context, userID int64, prompt, difficulty string) (models.Text, error) {
+	text, pooled, err := s.PooledText(userID, prompt, difficulty)
+	if pooled || err != nil {
+		return text, err
+	}
+	level, err := parseDifficulty(difficulty)
+	if err != nil {
+		return models.Text{}, err
+	}
+	if strings.TrimSpace(prompt) == "" {
+		prompt = DefaultPrompt
+	}
+
+	content, err := s.Generator.GenerateText(ctx, difficultyPrompt(prompt, level))
+	if err != nil {
+		return models.Text{}, fmt.Errorf("generating text: %w", err)
+	}
+	content = s.normalizeText(userID, content)
+	if content == "" {
+		return models.Text{}, fmt.Errorf("generating text: %w", errEmptyText)
+	}
+
+	return s.saveText(content, prompt, models.TextSourceLLM)
+}
+
+// GenerateTextStream generates a text from a prompt like GenerateText while
+// the provider streams it, and passes every complete sentence to emit as soon
+// as it arrived, normalized like the stored text. The text is stored once
+// the stream ends. Normalizing the complete text can change sentences that
+// were already emitted, e.g. when markdown markup spans sentences, so the
+// stored text is the one to type.
+func (s *Service) GenerateTextStream(ctx context.Context, userID int64, prompt, difficulty string, emit func(sentences string) error) (models.Text, error) {
+	level, err := parseDifficulty(difficulty)
+	if err != nil {
+		return models.Text{}, err
+	}
+	if strings.TrimSpace(prompt) == "" {
+		prompt = DefaultPrompt
+	}
+	opts := s.normalizeOptions(userID)
+
+	var raw strings.Builder
+	var emitted string
+	content, err := llm.StreamText(ctx, s.Generator, difficultyPrompt(prompt, level), func(chunk string) error {
+		raw.WriteString(chunk)
+		ready := completeSentences(normalize.Normalize(raw.String(), opts).Text)
+		if len(ready) <= len(emitted) || !strings.HasPrefix(ready, emitted) {
+			return nil
+		}
+		more := ready[len(emitted):]
+		emitted = ready
+		return emit(more)
+	})
+	if err != nil {
+		return models.Text{}, fmt.Errorf("generating text: %w", err)
+	}
+	content = normalizeWith(content, opts)
+	if content == "" {
+		return models.Text{}, fmt.Errorf("generating text: %w", errEmptyText)
+	}
+
+	return s.saveText(content, prompt, models.TextSourceLLM)
+}
+
+// completeSentences cuts normalized text after its last complete sentence,
+// i.e. the last sentence end that more text follows. Closing quotes and
+// brackets belong to the sentence.
+func completeSentences(text string) string {
+	for i := strings.LastIndex(text, " "); i > 0; i = strings.LastIndex(text[:i], " ") {
+		sentence := strings.TrimRight(text[:i], `"')]`)
+		if strings.HasSuffix(sentence, ".") || strings.HasSuffix(sentence, "!") || strings.HasSuffix(sentence, "?") {
+			return text[:i]
+		}
+	}
+	return ""
+}
+
+// GenerateOffline generates a text with the offline generator
+func (s *Service) GenerateOffline(opts textgen.Options) (models.Text, error) {
+	return s.saveText(textgen.Generate(opts), opts.Describe(), models.TextSourceOffline)
+}
+
+// GeneratePractice generates a text repeating words the user got wrong
+func (s *Service) GeneratePractice(ctx context.Context, userID int64, words []string) (models.Text, error) {
+	if len(words) == 0 {
+		return models.Text{}, Invalid("No words provided")
+	}
+
+	// Create a more specific prompt that ensures each word appears multiple times
+	prompt := fmt.Sprintf(
+		"Create a typing practice paragraph that includes EACH of these words AT LEAST 10 TIMES: %s. "+
+			"Make sure each word appears multiple times throughout the text. "+
+			"The text should be coherent but focus on repeating these words frequently for practice.",
+		strings.Join(words, ", "))
+
+	fmt.Printf("Generating practice with prompt: %s\n", prompt)
+
+	content, err := s.Generator.GenerateText(ctx, prompt)
+	if err != nil {
+		return models.Text{}, fmt.Errorf("generating practice text: %w", err)
+	}
+	content = s.normalizeText(userID, content)
+	if content == "" {
+		return models.Text{}, fmt.Errorf("generating practice text: %w", errEmptyText)
+	}
+
+	// Verify that each word appears multiple times
+	for _, word := range words {
+		count := strings.Count(strings.ToLower(content), strings.ToLower(word))
+		fmt.Printf("Word '%s' appears %d times in generated text\n", word, count)
+	}
+
+	return s.saveText(content, "Practice: "+strings.Join(words, ", "), models.TextSourcePractice)
+}
+
+// GenerateAdaptive generates a drill weighted toward the user's most
+// error-prone letters and slowest bigrams. The LLM is used unless offline is
+// set or no remote provider is configured.
+func (s *Service) GenerateAdaptive(ctx context.Context, userID int64, offline bool) (models.Text, error) {
+	// Build the weakness profile from the recent keystroke logs
+	logs, err := db.GetKeystrokeLogs(s.DB, userID, 200)
+	if err != nil {
+		return models.Text{}, err
+	}
+	bigrams := analytics.BigramLatencies(logs)
+	profile := drill.BuildProfile(analytics.CharErrorRates(logs), bigrams, 5)
+
+	var content string
+	offline = offline || s.Generator.Name() == "fallback"
+	if !offline {
+		content, err = s.Generator.GenerateText(ctx, drill.Prompt(profile))
+		if err != nil {
+			fmt.Printf("Adaptive generation failed, using word list: %v\n", err)
+			offline = true
+		} else {
+			content = s.normalizeText(userID, content)
+			offline = content == ""
+		}
+	}
+	if offline {
+		content = drill.Generate(profile, wordlist.Top(1000), 40, time.Now().UnixNano())
+	}
+
+	prompt := "Adaptive drill"
+	if !profile.Empty() {
+		var keys []string
+		for _, t := range append(profile.Chars, profile.Bigrams...) {
+			keys = append(keys, t.Key)
+		}
+		prompt += ": " + strings.Join(keys, ", ")
+	}
+
+	return s.saveText(content, prompt, models.TextSourceAdaptive)
+}
+
+// CodeLanguages returns the languages offered in code mode, or nil if code
+// mode isn't configured
+func (s *Service) CodeLanguages() []string {
+	if s.Code == nil {
+		return nil
+	}
+	return snippets.Languages()
+}
+
+// GenerateCode picks a snippet of a language from the configured code
+// directory. An empty language picks any supported language. Code is stored
+// as it is, without the normalization applied to prose.
+func (s *Service) GenerateCode(language string, seed int64) (models.Text, error) {
+	if s.Code == nil {
+		return models.Text{}, Invalid("Code mode is not configured on this server")
+	}
+	if language != "" && !slices.Contains(snippets.Languages(), language) {
+		return models.Text{}, Invalid("Unknown language %q", language)
+	}
+
+	snippet, err := s.Code.Snippet(language, seed)
+	if errors.Is(err, snippets.ErrNoSnippets) {
+		return models.Text{}, &Error{Code: CodeNotFound, Message: "No code found for that language"}
+	}
+	if err != nil {
+		return models.Text{}, err
+	}
+
+	return s.storeText(models.Text{
+		Content:  snippet.Content,
+		Prompt:   "Code: " + snippet.Path,
+		Kind:     models.TextKindCode,
+		Language: snippet.Language,
+		Source:   models.TextSourceCode,
+	})
+}
+
+// CreateText stores a text supplied by the user
+func (s *Service) CreateText(userID int64, content, prompt string) (models.Text, error) {
+	content = s.normalizeText(userID, content)
+	if content == "" {
+		return models.Text{}, Invalid("content must not be empty")
+	}
+	if prompt == "" {
+		prompt = "Custom text"
+	}
+
+	return s.saveText(content, prompt, models.TextSourceCustom)
+}
+
+// GetText returns a text by its ID. Passages of other users' collections
+// are not found.
+func (s *Service) GetText(userID, id int64) (models.Text, error) {
+	text, err := db.GetUserText(s.DB, userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Text{}, notFound("Text")
+	}
+	return text, err
+}
+
+// ListTexts returns a page of the texts a user may read, newest first
+func (s *Service) ListTexts(userID int64, limit, offset int) ([]models.Text, error) {
+	limit, offset = page(limit, offset)
+	return db.ListTexts(s.DB, userID, limit, offset)
+}
+
+// CheckTyping compares the input typed so far against a text
+func (s *Service) CheckTyping(userID, textID int64, input string, elapsed time.Duration) (models.TypingCheck, error) {
+	text, err := s.GetText(userID, textID)
+	if err != nil {
+		return models.TypingCheck{}, err
+	}
+
+	return scoring.Check(text.Content, input, elapsed), nil
+}
+
+// saveText stores a prose text and returns it
+func (s *Service) saveText(content, prompt, source string) (models.Text, error) {
+	return s.storeText(models.Text{Content: content, Prompt: prompt, Kind: models.TextKindProse, Source: source})
+}
+
+// storeText rates and stores a text and returns it with its ID filled in
+func (s *Service) storeText(text models.Text) (models.Text, error) {
+	text.Difficulty = scoring.Difficulty(text.Content)
+	textID, err := db.SaveText(s.DB, text)
+	if err != nil {
+		return models.Text{}, err
+	}
+
+	text.ID = textID
+	text.CreatedAt = time.Now()
+	return text, nil
+}
+
+// page clamps list paging parameters to sane values
+func page(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/janislaus/figure10/internal/auth"
+	"github.com/janislaus/figure10/internal/db"
+	"github.com/janislaus/figure10/internal/layouts"
+	"github.com/janislaus/figure10/internal/models"
+	"github.com/janislaus/figure10/internal/normalize"
+)
+
+// Login is an issued login token
+type Login struct {
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	User      models.User `json:"user"`
+}
+
+// Register creates a new account
+func (s *Service) Register(username, password string) (models.User, error) {
+	if err := auth.ValidateCredentials(username, password); err != nil {
+		return models.User{}, Invalid("%s", err.Error())
+	}
+
+	if _, err := db.GetUserByUsername(s.DB, username); err == nil {
+		return models.User{}, &Error{Code: CodeConflict, Message: "That username is already taken"}
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	userID, err := db.CreateUser(s.DB, username, hash)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	// The first account inherits the sessions typed before there were accounts
+	if users, err := db.CountUsers(s.DB); err != nil {
+		return models.User{}, err
+	} else if users == 1 {
+		adopted, err := db.AdoptOrphanedSessions(s.DB, userID)
+		if err != nil {
+			return models.User{}, err
+		}
+		if adopted > 0 {
+			fmt.Printf("Gave %d session(s) from before user accounts to %s\n", adopted, username)
+		}
+	}
+
+	return db.GetUserByID(s.DB, userID)
+}
+
+// Authenticate checks a username and password
+func (s *Service) Authenticate(username, password string) (models.User, error) {
+	user, err := db.GetUserByUsername(s.DB, username)
+	if err != nil || !auth.CheckPassword(user.PasswordHash, password) {
+		return models.User{}, &Error{Code: CodeUnauthorized, Message: "Invalid username or password"}
+	}
+	return user, nil
+}
+
+// StartLogin issues a new login token for the user
+func (s *Service) StartLogin(user models.User) (Login, error) {
+	token, err := auth.NewToken()
+	if err != nil {
+		return Login{}, err
+	}
+
+	expiresAt := time.Now().Add(auth.SessionDuration)
+	if err := db.CreateAuthSession(s.DB, token, user.ID, expiresAt); err != nil {
+		return Login{}, err
+	}
+
+	return Login{Token: token, ExpiresAt: expiresAt, User: user}, nil
+}
+
+// Logout revokes a login token
+func (s *Service) Logout(token string) error {
+	return db.DeleteAuthSession(s.DB, token)
+}
+
+// UserByToken looks up the user a login token belongs to
+func (s *Service) UserByToken(token string) (models.User, error) {
+	user, err := db.GetUserByAuthToken(s.DB, token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, &Error{Code: CodeUnauthorized, Message: "Not logged in"}
+	}
+	return user, err
+}
+
+// Settings returns the settings of a user
+func (s *Service) Settings(userID int64) (models.UserSettings, error) {
+	return db.GetUserSettings(s.DB, userID)
+}
+
+// Layout returns the keyboard layout of a user
+func (s *Service) Layout(userID int64) (*layouts.Layout, error) {
+	settings, err := db.GetUserSettings(s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	layout, ok := layouts.Get(settings.Layout)
+	if !ok {
+		layout, _ = layouts.Get(layouts.Default)
+	}
+	return layout, nil
+}
+
+// SaveSettings stores the settings of a user
+func (s *Service) SaveSettings(settings models.UserSettings) error {
+	if settings.MaxLength < 0 {
+		return Invalid("max_length must not be negative")
+	}
+	settings.AllowedChars = strings.TrimSpace(settings.AllowedChars)
+	if settings.Layout == "" {
+		settings.Layout = layouts.Default
+	}
+	if _, ok := layouts.Get(settings.Layout); !ok {
+		return Invalid("Unknown layout %q", settings.Layout)
+	}
+	return db.SaveUserSettings(s.DB, settings)
+}
+
+// normalizeText cleans up generated text according to the user's settings
+// before it is stored
+func (s *Service) normalizeText(userID int64, content string) string {
+	return normalizeWith(content, s.normalizeOptions(userID))
+}
+
+// normalizeOptions returns the normalization options of the user's settings
+func (s *Service) normalizeOptions(userID int64) normalize.Options {
+	settings, err := db.GetUserSettings(s.DB, userID)
+	if err != nil {
+		fmt.Printf("Failed to load settings, using defaults: %v\n", err)
+		settings = models.UserSettings{StripMarkdown: true, ASCIIPunctuation: true, Layout: layouts.Default}
+	}
+
+	return normalize.Options{
+		StripMarkdown:      settings.StripMarkdown,
+		ASCIIPunctuation:   settings.ASCIIPunctuation,
+		CollapseWhitespace: true,
+		MaxLength:          settings.MaxLength,
+		Allowed:            settings.AllowedChars,
+	}
+}
+
+// normalizeWith normalizes text and logs the characters that were removed
+func normalizeWith(content string, opts normalize.Options) string {
+	result := normalize.Normalize(content, opts)
+	if len(result.Removed) > 0 {
+		fmt.Printf("Removed characters outside the allowed set: %s\n", strings.Join(result.Removed, " "))
+	}
+
+	return result.Text
+}
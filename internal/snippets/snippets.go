@@ -0,0 +1,301 @@
+// Package snippets picks typing snippets from source code on disk
+package snippets
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Snippet limits
+const (
+	MinLines      = 4
+	MaxLines      = 14
+	MaxLineLength = 80
+	maxFileSize   = 512 << 10
+	tabWidth      = 4
+)
+
+// ErrNoSnippets is returned when no file of the language has a usable snippet
+var ErrNoSnippets = errors.New("no code snippets found")
+
+// extensions maps file extensions to language names
+var extensions = map[string]string{
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".go":    "go",
+	".java":  "java",
+	".js":    "javascript",
+	".mjs":   "javascript",
+	".kt":    "kotlin",
+	".lua":   "lua",
+	".php":   "php",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".sh":    "shell",
+	".sql":   "sql",
+	".swift": "swift",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+}
+
+// skippedDirs are directories that hold generated or third-party code
+var skippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+}
+
+// Languages returns the names of the supported languages, sorted
+func Languages() []string {
+	seen := map[string]bool{}
+	var languages []string
+	for _, language := range extensions {
+		if !seen[language] {
+			seen[language] = true
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// LanguageOf returns the language of a file by its extension, or "" if it
+// isn't a supported source file
+func LanguageOf(path string) string {
+	return extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// Snippet is a piece of code to type
+type Snippet struct {
+	Language string
+	Path     string // relative to the source root
+	Content  string
+}
+
+// Source picks snippets from the files below a directory. In a git work
+// tree only tracked files are used.
+type Source struct {
+	Root string
+}
+
+// NewSource creates a Source for the directory root
+func NewSource(root string) *Source {
+	return &Source{Root: root}
+}
+
+// Files returns the source files of a language, relative to the root. An
+// empty language matches every supported language.
+func (s *Source) Files(language string) ([]string, error) {
+	paths, err := s.gitFiles()
+	if err != nil {
+		paths, err = s.walkFiles()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var files []string
+	for _, path := range paths {
+		lang := LanguageOf(path)
+		if lang != "" && (language == "" || lang == language) {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// gitFiles lists the files tracked by git
+func (s *Source) gitFiles() ([]string, error) {
+	out, err := exec.Command("git", "-C", s.Root, "ls-files", "-z").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range strings.Split(string(out), "\x00") {
+		if path != "" {
+			files = append(files, filepath.FromSlash(path))
+		}
+	}
+	return files, nil
+}
+
+// walkFiles lists the files below the root, leaving out hidden and
+// third-party directories
+func (s *Source) walkFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(s.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != s.Root && (strings.HasPrefix(d.Name(), ".") || skippedDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.Root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	return files, err
+}
+
+// Snippet picks a snippet of a language. The same seed picks the same
+// snippet as long as the files don't change.
+func (s *Source) Snippet(language string, seed int64) (Snippet, error) {
+	files, err := s.Files(language)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(files), func(i, j int) {
+		files[i], files[j] = files[j], files[i]
+	})
+
+	for _, path := range files {
+		info, err := os.Stat(filepath.Join(s.Root, path))
+		if err != nil || info.Size() > maxFileSize {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.Root, path))
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			continue
+		}
+
+		candidates := Extract(string(data))
+		if len(candidates) == 0 {
+			continue
+		}
+		return Snippet{
+			Language: LanguageOf(path),
+			Path:     filepath.ToSlash(path),
+			Content:  candidates[rng.Intn(len(candidates))],
+		}, nil
+	}
+
+	return Snippet{}, ErrNoSnippets
+}
+
+// Extract splits source code into typeable snippets. A snippet starts at an
+// unindented line and ends before the next unindented line, so it usually
+// covers whole declarations. Snippets with overlong lines are left
+// out.
+func Extract(code string) []string {
+	lines := cleanLines(code)
+
+	var snippets []string
+	for start := 0; start < len(lines); start++ {
+		if !startsBlock(lines[start]) {
+			continue
+		}
+
+		end := -1
+		for i := start; i < len(lines) && i-start < MaxLines; i++ {
+			if len(lines[i]) > MaxLineLength {
+				break
+			}
+			if i-start+1 >= MinLines && endsBlock(lines, i) {
+				end = i + 1
+				break
+			}
+		}
+		if end < 0 {
+			continue
+		}
+
+		snippets = append(snippets, strings.Join(collapseBlankLines(lines[start:end]), "\n"))
+		start = end - 1
+	}
+	return snippets
+}
+
+// cleanLines splits code into lines with trailing whitespace removed and
+// tabs expanded, since tabs can't be typed in the browser
+func cleanLines(code string) []string {
+	code = strings.ReplaceAll(code, "\r\n", "\n")
+
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		trimmed := strings.TrimLeft(line, "\t")
+		indent := strings.Repeat(" ", (len(line)-len(trimmed))*tabWidth)
+		lines[i] = indent + strings.ReplaceAll(trimmed, "\t", " ")
+	}
+	return lines
+}
+
+// startsBlock reports whether a line can start a snippet
+func startsBlock(line string) bool {
+	if line == "" || indentation(line) > 0 {
+		return false
+	}
+	return !strings.ContainsAny(line[:1], "})]")
+}
+
+// endsBlock reports whether a snippet can end with line i, that is whether
+// the next non-blank line could start a snippet of its own. Snippets don't
+// end on a comment, which belongs to the code after it.
+func endsBlock(lines []string, i int) bool {
+	if lines[i] == "" || isComment(lines[i]) {
+		return false
+	}
+	for next := i + 1; next < len(lines); next++ {
+		if lines[next] != "" {
+			return startsBlock(lines[next])
+		}
+	}
+	return true
+}
+
+// isComment reports whether a line is a comment in one of the common styles
+func isComment(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "--"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// indentation returns the number of leading spaces of a non-blank line
+func indentation(line string) int {
+	if line == "" {
+		return -1
+	}
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// collapseBlankLines replaces runs of blank lines by a single one
+func collapseBlankLines(lines []string) []string {
+	var result []string
+	for i, line := range lines {
+		if line == "" && i > 0 && lines[i-1] == "" {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
@@ -0,0 +1,215 @@
+package textgen
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"unicode"
+
+	"github.com/janislaus/figure10/internal/wordlist"
+)
+
+// Vocabulary sizes that can be selected
+var VocabularyRanks = []int{200, 1000, 5000}
+
+// Options control the offline text generator
+type Options struct {
+	Words       int     // number of words in the text
+	Rank        int     // only use the Rank most common words
+	Punctuation float64 // 0 for no punctuation at all, 1 for punctuation in every sentence
+	Capitalize  bool    // start sentences with a capital letter
+	Numbers     bool    // mix numbers into the text
+	Seed        int64   // the same options and seed always produce the same text
+}
+
+// DefaultOptions returns options suitable for general typing practice
+func DefaultOptions(seed int64) Options {
+	return Options{
+		Words:       50,
+		Rank:        1000,
+		Punctuation: 0.5,
+		Capitalize:  true,
+		Numbers:     false,
+		Seed:        seed,
+	}
+}
+
+// Describe summarizes the options, including the seed needed to reproduce the text
+func (o Options) Describe() string {
+	var extras []string
+	if o.Capitalize {
+		extras = append(extras, "capitals")
+	}
+	if o.Numbers {
+		extras = append(extras, "numbers")
+	}
+	description := fmt.Sprintf("Offline: %d words from top %d, punctuation %.0f%%", o.Words, o.Rank, o.Punctuation*100)
+	if len(extras) > 0 {
+		description += ", " + strings.Join(extras, ", ")
+	}
+	return fmt.Sprintf("%s (seed %d)", description, o.Seed)
+}
+
+// generator holds the state of a single generation run
+type generator struct {
+	opts  Options
+	r     *rand.Rand
+	words []string
+}
+
+// sentenceTemplate decorates the words of a sentence with punctuation
+type sentenceTemplate func(g *generator, words []string) []string
+
+// sentenceTemplates are applied to a sentence depending on the punctuation
+// density. They keep the number of words, so word count tests get exactly
+// the words they ask for.
+var sentenceTemplates = []sentenceTemplate{
+	// Comma after a clause: "word word, word word."
+	func(g *generator, words []string) []string {
+		i := 1 + g.r.Intn(len(words)-2)
+		words[i] += ","
+		return words
+	},
+	// Question: "word word word?"
+	func(g *generator, words []string) []string {
+		words[len(words)-1] = strings.TrimSuffix(words[len(words)-1], ".") + "?"
+		return words
+	},
+	// Exclamation: "word word word!"
+	func(g *generator, words []string) []string {
+		words[len(words)-1] = strings.TrimSuffix(words[len(words)-1], ".") + "!"
+		return words
+	},
+	// Quoted phrase: word "word word" word.
+	func(g *generator, words []string) []string {
+		i := 1 + g.r.Intn(len(words)-3)
+		words[i] = "\"" + words[i]
+		words[i+1] += "\""
+		return words
+	},
+	// Parenthesis: word (word word) word.
+	func(g *generator, words []string) []string {
+		i := 1 + g.r.Intn(len(words)-3)
+		words[i] = "(" + words[i]
+		words[i+1] += ")"
+		return words
+	},
+	// List: word word: word, word, and word.
+	func(g *generator, words []string) []string {
+		if len(words) < 6 {
+			return words
+		}
+		n := len(words)
+		words[n-5] += ":"
+		words[n-4] += ","
+		words[n-3] += ","
+		words[n-2] = "and"
+		return words
+	},
+	// Two clauses: word word; word word.
+	func(g *generator, words []string) []string {
+		i := 1 + g.r.Intn(len(words)-2)
+		words[i] += ";"
+		return words
+	},
+	// Compound: word-word, counted as a single word
+	func(g *generator, words []string) []string {
+		i := g.r.Intn(len(words) - 1)
+		words[i] += "-" + g.words[g.r.Intn(len(g.words))]
+		return words
+	},
+	// Possessive: word's
+	func(g *generator, words []string) []string {
+		i := g.r.Intn(len(words) - 1)
+		words[i] += "'s"
+		return words
+	},
+}
+
+// Generate builds a text from the embedded word-frequency list
+func Generate(opts Options) string {
+	if opts.Words <= 0 {
+		opts.Words = DefaultOptions(0).Words
+	}
+
+	g := &generator{
+		opts:  opts,
+		r:     rand.New(rand.NewSource(opts.Seed)),
+		words: wordlist.Top(opts.Rank),
+	}
+
+	var sentences []string
+	remaining := opts.Words
+	for remaining > 0 {
+		n := 5 + g.r.Intn(8)
+		if n > remaining {
+			n = remaining
+		}
+		// Very short leftovers are folded into the last sentence
+		if remaining-n < 3 {
+			n = remaining
+		}
+		sentences = append(sentences, g.sentence(n))
+		remaining -= n
+	}
+
+	return strings.Join(sentences, " ")
+}
+
+// sentence builds a single sentence of n words
+func (g *generator) sentence(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		if g.opts.Numbers && g.r.Float64() < 0.08 {
+			words[i] = g.number()
+			continue
+		}
+		words[i] = g.words[g.r.Intn(len(g.words))]
+	}
+
+	if g.opts.Punctuation > 0 {
+		words[n-1] += "."
+		if n >= 4 && g.r.Float64() < g.opts.Punctuation {
+			template := sentenceTemplates[g.r.Intn(len(sentenceTemplates))]
+			words = template(g, words)
+		}
+	}
+
+	if g.opts.Capitalize {
+		words[0] = capitalize(words[0])
+		for i, word := range words {
+			// The pronoun is capitalized anywhere in the sentence
+			if strings.TrimFunc(word, unicode.IsPunct) == "i" {
+				words[i] = capitalize(word)
+			}
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// number returns a number in one of a few common shapes
+func (g *generator) number() string {
+	switch g.r.Intn(4) {
+	case 0:
+		return fmt.Sprint(g.r.Intn(10))
+	case 1:
+		return fmt.Sprint(10 + g.r.Intn(90))
+	case 2:
+		return fmt.Sprint(1900 + g.r.Intn(130))
+	default:
+		return fmt.Sprint(100 + g.r.Intn(9900))
+	}
+}
+
+// capitalize upper-cases the first letter of a word, skipping leading punctuation
+func capitalize(word string) string {
+	runes := []rune(word)
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			runes[i] = unicode.ToUpper(r)
+			break
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,32 @@
+package textgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateWordCount(t *testing.T) {
+	for _, words := range []int{10, 25, 50, 100} {
+		for seed := int64(0); seed < 200; seed++ {
+			opts := DefaultOptions(seed)
+			opts.Words = words
+			opts.Punctuation = 1
+			opts.Numbers = true
+			text := Generate(opts)
+			if got := len(strings.Fields(text)); got != words {
+				t.Fatalf("Generate(%d words, seed %d) has %d words: %q", words, seed, got, text)
+			}
+		}
+	}
+}
+
+func TestGenerateCapitalizesI(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		text := Generate(DefaultOptions(seed))
+		for _, word := range strings.Fields(text) {
+			if strings.Trim(word, `.,;:!?"()`) == "i" {
+				t.Fatalf("seed %d leaves %q lowercase: %q", seed, word, text)
+			}
+		}
+	}
+}
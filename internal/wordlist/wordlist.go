@@ -0,0 +1,32 @@
+package wordlist
+
+import (
+	_ "embed"
+	"strings"
+	"sync"
+)
+
+//go:embed english.txt
+var englishFile string
+
+var (
+	englishOnce  sync.Once
+	englishWords []string
+)
+
+// English returns the embedded English words, most common first
+func English() []string {
+	englishOnce.Do(func() {
+		englishWords = strings.Fields(englishFile)
+	})
+	return englishWords
+}
+
+// Top returns the n most common English words
+func Top(n int) []string {
+	words := English()
+	if n <= 0 || n > len(words) {
+		return words
+	}
+	return words[:n]
+}
@@ -0,0 +1,197 @@
+// Play back a recorded session: the text with the cursor, mistakes and
+// corrections at each moment of the session, a scrubber to move through it
+// and a playhead over the speed graph. The strokes come from the server,
+// annotated with the position they were applied to and the length of the
+// input after them.
+document.addEventListener('DOMContentLoaded', initReplay);
+
+function initReplay() {
+    const container = document.getElementById('replay');
+    if (!container) {
+        return;
+    }
+    
+    const expected = splitGraphemes(container.dataset.content);
+    const strokes = JSON.parse(container.dataset.strokes);
+    const pauses = JSON.parse(container.dataset.pauses);
+    const duration = parseInt(container.dataset.duration, 10);
+    
+    const textDisplay = document.getElementById('replay-text');
+    const playButton = document.getElementById('replay-play');
+    const rateSelect = document.getElementById('replay-rate');
+    const timeDisplay = document.getElementById('replay-time');
+    const status = document.getElementById('replay-status');
+    const scrubber = document.getElementById('replay-scrubber');
+    const speedGraph = document.getElementById('replay-speed');
+    const playhead = document.getElementById('replay-playhead');
+    
+    let time = 0;
+    let playing = false;
+    let lastFrame = null;
+    let shownStrokes = -1;
+    
+    // Apply the strokes up to a time and show the resulting input
+    function render() {
+        let count = 0;
+        while (count < strokes.length && strokes[count].timestamp <= time) {
+            count++;
+        }
+        
+        if (count !== shownStrokes) {
+            shownStrokes = count;
+            showInput(count);
+        }
+        showStatus(count);
+        
+        scrubber.value = time;
+        timeDisplay.textContent = formatMillis(time) + ' / ' + formatMillis(duration);
+        const x0 = parseFloat(speedGraph.dataset.x0);
+        const dx = parseFloat(speedGraph.dataset.dx);
+        playhead.style.left = (x0 + (time / 1000 - 0.5) * dx) + '%';
+    }
+    
+    // Show the text as it was after the first count strokes
+    function showInput(count) {
+        const input = [];
+        const corrected = new Set();
+        for (let i = 0; i < count; i++) {
+            const s = strokes[i];
+            if (s.backspace) {
+                input.length = s.length;
+                continue;
+            }
+            input.length = s.index;
+            input.push(s.key);
+            if (!s.correct) {
+                corrected.add(s.index);
+            }
+            // Indentation filled in after a line break of a code text
+            while (input.length < s.length) {
+                input.push(expected[input.length]);
+            }
+        }
+        
+        let html = '';
+        for (let i = 0; i < expected.length; i++) {
+            const classes = [];
+            let title = '';
+            if (i >= input.length) {
+                classes.push('text-gray-300');
+            } else if (input[i] !== expected[i]) {
+                classes.push('text-red-500', 'bg-red-900');
+                title = ` title="Typed ${escapeAttribute(input[i])}"`;
+            } else if (corrected.has(i)) {
+                classes.push('replay-corrected');
+            } else {
+                classes.push('text-white');
+            }
+            if (i === input.length) {
+                classes.push('replay-cursor');
+            }
+            html += `<span class="${classes.join(' ')}"${title}>${displayChar(expected[i])}</span>`;
+        }
+        textDisplay.innerHTML = html;
+    }
+    
+    // Say when the typist was pausing or had just pressed Backspace
+    function showStatus(count) {
+        const pause = pauses.find(p => time >= p.start_ms && time < p.start_ms + p.duration_ms);
+        if (pause) {
+            status.textContent = `Pause ${((time - pause.start_ms) / 1000).toFixed(1)}s of ${(pause.duration_ms / 1000).toFixed(1)}s`;
+        } else if (count > 0 && strokes[count - 1].backspace && time - strokes[count - 1].timestamp < 300) {
+            status.textContent = '⌫ Backspace';
+        } else {
+            status.textContent = '';
+        }
+    }
+    
+    function frame(now) {
+        if (!playing) {
+            return;
+        }
+        if (lastFrame !== null) {
+            time += (now - lastFrame) * parseFloat(rateSelect.value);
+        }
+        lastFrame = now;
+        if (time >= duration) {
+            time = duration;
+            stop();
+        }
+        render();
+        if (playing) {
+            requestAnimationFrame(frame);
+        }
+    }
+    
+    function play() {
+        // Playing at the end starts over
+        if (time >= duration) {
+            time = 0;
+        }
+        playing = true;
+        lastFrame = null;
+        playButton.textContent = 'Pause';
+        requestAnimationFrame(frame);
+    }
+    
+    function stop() {
+        playing = false;
+        playButton.textContent = 'Play';
+    }
+    
+    function seek(to) {
+        time = Math.max(0, Math.min(duration, to));
+        lastFrame = null;
+        render();
+    }
+    
+    playButton.addEventListener('click', function() {
+        if (playing) {
+            stop();
+        } else {
+            play();
+        }
+    });
+    
+    scrubber.addEventListener('input', function() {
+        seek(parseInt(scrubber.value, 10));
+    });
+    
+    // Start a second before a pause to see what led up to it
+    document.querySelectorAll('.replay-seek').forEach(function(button) {
+        button.addEventListener('click', function() {
+            seek(parseInt(button.dataset.seek, 10) - 1000);
+            play();
+            container.scrollIntoView({ behavior: 'smooth' });
+        });
+    });
+    
+    // Space plays and pauses, unless a control has the focus
+    document.addEventListener('keydown', function(e) {
+        if (e.key === ' ' && !['BUTTON', 'SELECT', 'INPUT'].includes(e.target.tagName)) {
+            e.preventDefault();
+            playButton.click();
+        }
+    });
+    
+    // Links from the session page start a second before a pause
+    const start = parseInt(new URLSearchParams(window.location.search).get('t'), 10);
+    if (!isNaN(start)) {
+        time = Math.max(0, Math.min(duration, start - 1000));
+    }
+    
+    render();
+}
+
+// Format a time of the replay as minutes, seconds and tenths
+function formatMillis(ms) {
+    ms = Math.round(ms / 100) * 100;
+    const minutes = Math.floor(ms / 60000);
+    const seconds = (ms % 60000) / 1000;
+    return minutes + ':' + (seconds < 10 ? '0' : '') + seconds.toFixed(1);
+}
+
+// Escape a character for an attribute value
+function escapeAttribute(ch) {
+    return displayChar(ch).replace(/"/g, '&quot;');
+}
@@ -1,6 +1,7 @@
 document.addEventListener('DOMContentLoaded', function() {
     // Initialize typing functionality
     initTyping();
+    initKeyboard();
     
     // Also listen for HTMX content swaps (when new text is generated)
     document.body.addEventListener('htmx:afterSwap', function(event) {
@@ -9,6 +10,9 @@ document.addEventListener('DOMContentLoaded', function() {
     });
 });
 
+// The event stream of the text being generated, if any
+let textStream = null;
+
 function initTyping() {
     const textDisplay = document.getElementById('text-display');
     const textContainer = document.getElementById('typing-text');
@@ -18,14 +22,53 @@ function initTyping() {
         return;
     }
     
+    // Generated texts stream in sentence by sentence. The exercise starts
+    // with the first sentence, and the text gets its ID once it is stored.
+    const streamUrl = textContainer.dataset.stream;
+    if (textStream && !streamUrl) {
+        // Another exercise replaced the text that was being generated
+        textStream.close();
+        textStream = null;
+    }
+    if (streamUrl && !textContainer.dataset.content) {
+        streamText(textContainer, streamUrl);
+        return;
+    }
+    let isStreaming = !!streamUrl;
+    
     const textId = textContainer.dataset.textId;
     const originalText = textContainer.dataset.content;
     
-    if (!textId || !originalText) {
+    if ((!textId && !isStreaming) || !originalText) {
         console.error("Missing text ID or content");
         return;
     }
     
+    // Sessions are created for the text ID, which streamed texts only have
+    // once they are complete
+    let resolveTextId = null;
+    let rejectTextId = null;
+    const textIdPromise = isStreaming ? new Promise((resolve, reject) => {
+        resolveTextId = resolve;
+        rejectTextId = reject;
+    }) : Promise.resolve(textId);
+    
+    // Work on user-perceived characters, so umlauts, emoji and combining
+    // marks count as a single character like they do on the server. Streamed
+    // tests append to them while typing.
+    const originalChars = splitGraphemes(originalText);
+    
+    // Code texts take Enter for line breaks and indent new lines automatically
+    const isCode = textContainer.dataset.kind === 'code';
+    
+    // The test decides when the session ends: timed tests after their time
+    // limit, sudden death at the first mistake, the others with the text
+    const test = textContainer.dataset.test || 'text';
+    const testMode = textContainer.dataset.testMode || 'text';
+    const testParam = parseInt(textContainer.dataset.testParam || '0', 10);
+    const isStreamed = testMode === 'time' || testMode === 'sudden-death';
+    let isExtending = false;
+    
     console.log("Initializing typing with text ID:", textId);
     
     // Remove any existing cursor before creating a new one
@@ -40,15 +83,22 @@ function initTyping() {
     document.body.appendChild(cursor);
     
     // Variables to track typing state
-    let typedText = '';
+    let typedChars = [];
+    let autoIndented = []; // whether each typed character was filled in automatically
     let startTime = null;
     let isSessionActive = false;
+    let isSessionOver = false;
     let errorCount = 0;
-    let errorDetails = [];
     let wordsWithErrors = new Set();
     let timerInterval = null;
     let metricsUpdateInterval = null;
     
+    // Variables to track the server-side session and its keystroke log
+    let sessionPromise = null;
+    let keystrokeSeq = 0;
+    let pendingKeystrokes = [];
+    let keystrokeFlushInterval = null;
+    
     // Create a timer element if it doesn't exist
     let timerElement = document.getElementById('typing-timer');
     if (!timerElement) {
@@ -59,6 +109,9 @@ function initTyping() {
         textContainer.parentNode.insertBefore(timerElement, textContainer);
     }
     
+    // Timed tests show the time left
+    timerElement.textContent = formatTime(testMode === 'time' ? testParam * 1000 : 0);
+    
     // Initialize the display
     initializeDisplay();
     
@@ -71,6 +124,40 @@ function initTyping() {
         textDisplay.focus();
     });
     
+    // Add the sentences of a streamed text as they arrive
+    textContainer.addEventListener('text-chunk', function(e) {
+        originalChars.push(...splitGraphemes(e.detail));
+        refreshDisplay();
+    });
+    
+    // Switch to the stored text once the stream is complete. It is the one
+    // the session is scored on and can differ from the streamed sentences.
+    textContainer.addEventListener('text-done', function(e) {
+        isStreaming = false;
+        textContainer.dataset.textId = e.detail.text_id;
+        originalChars.splice(0, originalChars.length, ...splitGraphemes(e.detail.content));
+        resolveTextId(e.detail.text_id);
+        refreshDisplay();
+        
+        document.getElementById('typing-feedback').textContent =
+            "Ready to start typing... (Press ESC to end session early)";
+        
+        // The typist may have caught up with the text already
+        if (isSessionActive && typedChars.length >= originalChars.length) {
+            endSession("Great job! You've completed the text.");
+        }
+    });
+    
+    // Without a stored text there is no session to score
+    textContainer.addEventListener('text-failed', function(e) {
+        isStreaming = false;
+        isSessionActive = false;
+        isSessionOver = true;
+        stopTimer();
+        rejectTextId(new Error(e.detail));
+        cursor.style.display = 'none';
+    });
+    
     // Add a variable to track typing activity
     let typingTimer = null;
     const typingDelay = 100; // 100ms delay before considering typing stopped
@@ -82,6 +169,11 @@ function initTyping() {
         // Prevent default behavior for all keys
         e.preventDefault();
         
+        // A finished test takes no more keys
+        if (isSessionOver) {
+            return;
+        }
+        
         // Show solid cursor during typing (no blink)
         cursor.classList.add('typing');
         
@@ -99,22 +191,30 @@ function initTyping() {
         if (e.key === 'Escape') {
             console.log("Escape pressed, ending session");
             if (isSessionActive) {
-                isSessionActive = false;
-                stopTimer();
-                submitResult();
-                
-                // Show completion message
-                showCompletionMessage("Session ended with Escape key");
+                endSession("Session ended with Escape key");
             }
             return;
         }
         
+        // In code mode Enter types a line break
+        const key = (isCode && e.key === 'Enter') ? '\n' : e.key;
+        
+        // Wait for the rest of a streamed text rather than typing past it
+        if (isStreaming && isCharacterKey(key) && typedChars.length >= originalChars.length) {
+            return;
+        }
+        
         // If session is not active, start it on the first key press
-        if (!isSessionActive && e.key.length === 1) {
+        if (!isSessionActive && isCharacterKey(key)) {
             console.log("Starting session");
-            startTime = new Date();
+            // The monotonic clock keeps keystroke times in order when the
+            // system clock changes
+            startTime = performance.now();
             isSessionActive = true;
             
+            // Create the session on the server before recording keystrokes
+            startSession();
+            
             // Start the timer and metrics updates
             startTimer();
             startMetricsUpdates();
@@ -122,43 +222,93 @@ function initTyping() {
         
         // Handle Backspace
         if (e.key === 'Backspace') {
-            if (typedText.length > 0) {
-                typedText = typedText.slice(0, -1);
-                updateDisplay(typedText);
+            if (isSessionActive) {
+                recordKeystroke(e.key, true);
+            }
+            // Automatic indentation is removed together with its line break
+            while (autoIndented.length > 0 && autoIndented[autoIndented.length - 1]) {
+                typedChars.pop();
+                autoIndented.pop();
+            }
+            if (typedChars.length > 0) {
+                typedChars.pop();
+                autoIndented.pop();
+                updateDisplay(typedChars);
             }
             return;
         }
         
         // Handle regular typing
-        if (e.key.length === 1) {
+        if (isCharacterKey(key)) {
+            recordKeystroke(key, false);
+            
+            // A lone combining mark completes the previous character
+            let typed = key.normalize('NFC');
+            if (isCombiningMark(typed) && typedChars.length > 0 && !autoIndented[autoIndented.length - 1]) {
+                typed = (typedChars.pop() + typed).normalize('NFC');
+                autoIndented.pop();
+            }
+            
             // Check if this character is an error
-            if (typedText.length < originalText.length && e.key !== originalText[typedText.length]) {
+            let isError = false;
+            if (typedChars.length < originalChars.length && typed !== originalChars[typedChars.length]) {
+                isError = true;
                 errorCount++;
                 document.getElementById('errors').textContent = errorCount;
+                flashWrongKey(key);
             }
             
-            typedText += e.key;
-            updateDisplay(typedText);
+            const correctLineBreak = typed === '\n' && originalChars[typedChars.length] === '\n';
+            typedChars.push(typed);
+            autoIndented.push(false);
+            
+            // Fill in the indentation of the next line, which isn't typed
+            if (isCode && correctLineBreak) {
+                while (typedChars.length < originalChars.length && isIndent(originalChars[typedChars.length])) {
+                    typedChars.push(originalChars[typedChars.length]);
+                    autoIndented.push(true);
+                }
+            }
+            updateDisplay(typedChars);
+            
+            // Sudden death ends at the first mistake
+            if (testMode === 'sudden-death' && isError) {
+                console.log("Mistake in sudden death, ending session");
+                endSession("Sudden death! The test ended at your first mistake.");
+                return;
+            }
+            
+            // Fetch more text before a streamed test runs out of it
+            if (isStreamed && originalChars.length - typedChars.length < 100) {
+                extendText();
+            }
             
             // Check if we've completed the text
-            if (typedText.length >= originalText.length) {
+            if (!isStreaming && typedChars.length >= originalChars.length) {
                 console.log("Text completed, ending session");
-                isSessionActive = false;
-                stopTimer();
-                submitResult();
-                
-                // Show completion message
-                showCompletionMessage("Great job! You've completed the text.");
+                endSession("Great job! You've completed the text.");
             }
         }
     });
     
+    // Function to end the session, score it on the server and show the result
+    function endSession(message) {
+        isSessionActive = false;
+        isSessionOver = true;
+        stopTimer();
+        submitResult();
+        showNextKey(null);
+        
+        // Show completion message
+        showCompletionMessage(message);
+    }
+    
     // Function to initialize the display
     function initializeDisplay() {
         let displayHTML = '';
         
-        for (let i = 0; i < originalText.length; i++) {
-            displayHTML += `<span class="text-gray-300">${originalText[i]}</span>`;
+        for (let i = 0; i < originalChars.length; i++) {
+            displayHTML += `<span class="text-gray-300">${displayChar(originalChars[i])}</span>`;
         }
         
         textDisplay.innerHTML = displayHTML;
@@ -167,6 +317,15 @@ function initTyping() {
         updateCursorPosition(0);
     }
     
+    // Function to show text that was added or replaced
+    function refreshDisplay() {
+        if (typedChars.length > 0) {
+            updateDisplay(typedChars);
+        } else {
+            initializeDisplay();
+        }
+    }
+    
     // Function to update the display based on typed text
     function updateDisplay(currentInput) {
         let displayHTML = '';
@@ -176,44 +335,37 @@ function initTyping() {
         let wordStartIndex = 0;
         let wordWithError = false;
         
-        for (let i = 0; i < originalText.length; i++) {
+        for (let i = 0; i < originalChars.length; i++) {
             // Track words by looking for spaces or newlines
-            if (i === 0 || originalText[i-1] === ' ' || originalText[i-1] === '\n') {
+            if (i === 0 || originalChars[i-1] === ' ' || originalChars[i-1] === '\n') {
                 wordStartIndex = i;
                 currentWord = '';
                 wordWithError = false;
             }
             
             // Build the current word
-            if (originalText[i] !== ' ' && originalText[i] !== '\n') {
-                currentWord += originalText[i];
+            if (originalChars[i] !== ' ' && originalChars[i] !== '\n') {
+                currentWord += originalChars[i];
             }
             
             if (i < currentInput.length) {
-                if (currentInput[i] === originalText[i]) {
+                if (currentInput[i] === originalChars[i]) {
                     // Correct character
-                    displayHTML += `<span class="text-white">${originalText[i]}</span>`;
+                    displayHTML += `<span class="text-white">${displayChar(originalChars[i])}</span>`;
                 } else {
                     // Incorrect character - mark the word as having an error
-                    displayHTML += `<span class="text-red-500 bg-red-900">${originalText[i]}</span>`;
+                    displayHTML += `<span class="text-red-500 bg-red-900">${displayChar(originalChars[i])}</span>`;
                     
                     // Mark this word as having an error
                     wordWithError = true;
-                    
-                    // Add error details
-                    errorDetails.push({
-                        expected_char: originalText[i],
-                        typed_char: currentInput[i],
-                        position: i
-                    });
                 }
             } else {
                 // Not yet typed
-                displayHTML += `<span class="text-gray-300">${originalText[i]}</span>`;
+                displayHTML += `<span class="text-gray-300">${displayChar(originalChars[i])}</span>`;
             }
             
             // If we reach a space, newline, or end of text, we've completed a word
-            if (originalText[i] === ' ' || originalText[i] === '\n' || i === originalText.length - 1) {
+            if (originalChars[i] === ' ' || originalChars[i] === '\n' || i === originalChars.length - 1) {
                 // If this word had an error and it's not empty, add it to the set
                 if (wordWithError && currentWord.trim().length > 0) {
                     wordsWithErrors.add(currentWord.trim());
@@ -233,8 +385,10 @@ function initTyping() {
     
     // Function to update cursor position
     function updateCursorPosition(position) {
+        showNextKey(originalChars[position]);
+        
         // Find the position where the cursor should be
-        if (position < originalText.length) {
+        if (position < originalChars.length) {
             const spans = textDisplay.querySelectorAll('span');
             if (spans.length > position) {
                 const currentSpan = spans[position];
@@ -283,24 +437,30 @@ function initTyping() {
     
     // Function to update metrics
     function updateMetrics() {
+        // Automatic indentation doesn't count as typed
+        let typedCount = 0;
+        let correctChars = 0;
+        for (let i = 0; i < typedChars.length; i++) {
+            if (autoIndented[i]) {
+                continue;
+            }
+            typedCount++;
+            if (i < originalChars.length && typedChars[i] === originalChars[i]) {
+                correctChars++;
+            }
+        }
+        
         // Calculate WPM
-        const elapsedTime = startTime ? (new Date() - startTime) / 1000 / 60 : 0; // in minutes
+        const elapsedTime = startTime ? (performance.now() - startTime) / 1000 / 60 : 0; // in minutes
         let wpm = 0;
         if (elapsedTime > 0) {
-            wpm = (typedText.length / 5) / elapsedTime;
+            wpm = (typedCount / 5) / elapsedTime;
         }
         
         // Calculate accuracy
-        let correctChars = 0;
-        for (let i = 0; i < typedText.length; i++) {
-            if (i < originalText.length && typedText[i] === originalText[i]) {
-                correctChars++;
-            }
-        }
-        
         let accuracy = 100;
-        if (typedText.length > 0) {
-            accuracy = (correctChars / typedText.length) * 100;
+        if (typedCount > 0) {
+            accuracy = (correctChars / typedCount) * 100;
         }
         
         // Update the UI
@@ -309,23 +469,36 @@ function initTyping() {
         document.getElementById('errors').textContent = errorCount;
     }
     
-    // Function to start the timer
+    // Function to start the timer. Timed tests count down and end the
+    // session when the time is up.
     function startTimer() {
         if (timerInterval) clearInterval(timerInterval);
         
         timerInterval = setInterval(function() {
-            const elapsedTime = new Date() - startTime;
-            const minutes = Math.floor(elapsedTime / 60000);
-            const seconds = Math.floor((elapsedTime % 60000) / 1000);
-            const milliseconds = Math.floor((elapsedTime % 1000) / 10);
-            
-            timerElement.textContent = 
-                (minutes < 10 ? '0' : '') + minutes + ':' + 
-                (seconds < 10 ? '0' : '') + seconds + '.' + 
-                (milliseconds < 10 ? '0' : '') + milliseconds;
+            let elapsedTime = performance.now() - startTime;
+            if (testMode === 'time') {
+                elapsedTime = testParam * 1000 - elapsedTime;
+                if (elapsedTime <= 0) {
+                    timerElement.textContent = formatTime(0);
+                    endSession("Time's up!");
+                    return;
+                }
+            }
+            timerElement.textContent = formatTime(elapsedTime);
         }, 10);
     }
     
+    // Function to format a time in milliseconds as mm:ss.cc
+    function formatTime(elapsedTime) {
+        const minutes = Math.floor(elapsedTime / 60000);
+        const seconds = Math.floor((elapsedTime % 60000) / 1000);
+        const milliseconds = Math.floor((elapsedTime % 1000) / 10);
+        
+        return (minutes < 10 ? '0' : '') + minutes + ':' + 
+            (seconds < 10 ? '0' : '') + seconds + '.' + 
+            (milliseconds < 10 ? '0' : '') + milliseconds;
+    }
+    
     // Function to stop the timer
     function stopTimer() {
         if (timerInterval) {
@@ -337,6 +510,110 @@ function initTyping() {
             clearInterval(metricsUpdateInterval);
             metricsUpdateInterval = null;
         }
+        
+        if (keystrokeFlushInterval) {
+            clearInterval(keystrokeFlushInterval);
+            keystrokeFlushInterval = null;
+        }
+    }
+    
+    // Function to create the session on the server
+    function startSession() {
+        sessionPromise = textIdPromise.then(id => {
+            return fetch('/start-session', {
+                method: 'POST',
+                body: new URLSearchParams({ text_id: id, test: test })
+            });
+        })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error('Failed to start session: ' + response.statusText);
+            }
+            return response.json();
+        })
+        .then(data => {
+            console.log("Session started:", data.session_id);
+            return data.session_id;
+        });
+        
+        // Stream the keystroke log to the server every second
+        if (keystrokeFlushInterval) clearInterval(keystrokeFlushInterval);
+        keystrokeFlushInterval = setInterval(flushKeystrokes, 1000);
+    }
+    
+    // Function to append the next chunk of a streamed test to the text
+    function extendText() {
+        if (isExtending || !sessionPromise) {
+            return;
+        }
+        isExtending = true;
+        
+        sessionPromise.then(sessionId => {
+            return fetch('/extend-text', {
+                method: 'POST',
+                body: new URLSearchParams({ session_id: sessionId })
+            });
+        })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error('Failed to extend text: ' + response.statusText);
+            }
+            return response.json();
+        })
+        .then(data => {
+            originalChars.push(...splitGraphemes(data.content));
+            if (isSessionActive) {
+                updateDisplay(typedChars);
+            }
+            isExtending = false;
+        })
+        .catch(error => {
+            // Leave isExtending set, so a failing stream isn't retried on
+            // every key
+            console.error("Error extending text:", error);
+        });
+    }
+    
+    // Function to record a raw keystroke event
+    function recordKeystroke(key, isBackspace) {
+        pendingKeystrokes.push({
+            seq: keystrokeSeq++,
+            key: key,
+            timestamp: Math.round(performance.now() - startTime),
+            position: typedChars.length,
+            backspace: isBackspace
+        });
+    }
+    
+    // Function to send the recorded keystrokes to the server
+    function flushKeystrokes() {
+        if (!sessionPromise || pendingKeystrokes.length === 0) {
+            return sessionPromise || Promise.resolve(null);
+        }
+        
+        const events = pendingKeystrokes;
+        pendingKeystrokes = [];
+        
+        return sessionPromise.then(sessionId => {
+            return fetch('/keystrokes', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({
+                    session_id: sessionId,
+                    events: events
+                })
+            })
+            .then(response => {
+                if (!response.ok) {
+                    // Keep the events so they are sent with the next batch
+                    pendingKeystrokes = events.concat(pendingKeystrokes);
+                    throw new Error('Failed to record keystrokes: ' + response.statusText);
+                }
+                return sessionId;
+            });
+        });
     }
     
     // Function to start metrics updates
@@ -352,36 +629,135 @@ function initTyping() {
     
     // Function to submit the result
     function submitResult() {
-        // Collect error words
-        const errorWords = Array.from(wordsWithErrors);
-        
-        // Create the result object
-        const result = {
-            text_id: parseInt(textId),
-            wpm: parseFloat(document.getElementById('wpm').textContent),
-            accuracy: parseFloat(document.getElementById('accuracy').textContent),
-            errors: errorCount,
-            error_details: errorDetails,
-            error_words: errorWords
-        };
-        
-        // Submit the result
-        fetch('/submit-result', {
-            method: 'POST',
-            headers: {
-                'Content-Type': 'application/json',
-            },
-            body: JSON.stringify(result)
+        if (!sessionPromise) {
+            return;
+        }
+        
+        // Send the remaining keystrokes, then let the server score the session
+        flushKeystrokes()
+        .then(() => sessionPromise)
+        .then(sessionId => {
+            return fetch('/submit-result', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ session_id: sessionId })
+            });
+        })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error('Failed to submit result: ' + response.statusText);
+            }
+            return response.json();
         })
-        .then(response => response.json())
         .then(data => {
             console.log("Result submitted:", data);
+            showServerResult(data.result);
         })
         .catch(error => {
             console.error("Error submitting result:", error);
         });
     }
     
+    // Function to replace the local metrics with the server's computation
+    function showServerResult(result) {
+        if (!result) {
+            return;
+        }
+        
+        document.getElementById('wpm').textContent = result.wpm.toFixed(1);
+        document.getElementById('accuracy').textContent = result.accuracy.toFixed(1) + '%';
+        document.getElementById('errors').textContent = result.errors;
+        
+        const summary = document.getElementById('completion-summary');
+        if (summary) {
+            summary.textContent = `WPM: ${result.wpm.toFixed(1)} | ` +
+                `Accuracy: ${result.accuracy.toFixed(1)}% | ` +
+                `Errors: ${result.errors}`;
+            if (result.test && result.test.mode !== 'text') {
+                summary.textContent = `Test: ${testName(result.test)} | ` + summary.textContent;
+            }
+            
+            // Show the standard metrics computed from the keystroke log
+            const metricsLine = document.createElement('p');
+            metricsLine.className = 'mt-1 text-sm';
+            metricsLine.textContent = `Raw: ${result.raw_wpm.toFixed(1)} WPM | ` +
+                `Net: ${result.net_wpm.toFixed(1)} WPM | ` +
+                `Keystroke accuracy: ${result.keystroke_accuracy.toFixed(1)}% | ` +
+                `Uncorrected errors: ${result.uncorrected_errors} | ` +
+                `Consistency: ${result.consistency.toFixed(0)}%`;
+            summary.after(metricsLine);
+            
+            // Announce new personal bests below the summary
+            const bests = (result.personal_bests || []).map(describePersonalBest);
+            if (bests.length > 0) {
+                const bestsLine = document.createElement('p');
+                bestsLine.className = 'mt-2 font-bold text-yellow-300';
+                bestsLine.textContent = 'New personal best: ' + bests.join(', ');
+                metricsLine.after(bestsLine);
+            }
+            
+            // Tell how the session did on the lesson of a lesson text
+            if (result.lesson) {
+                summary.parentElement.appendChild(lessonLine(result.lesson));
+            }
+        }
+    }
+    
+    // Function to describe the lesson result for the completion message
+    function lessonLine(lesson) {
+        const line = document.createElement('p');
+        line.className = 'mt-2 font-bold';
+        if (lesson.passed) {
+            line.classList.add('text-yellow-300');
+            line.textContent = `Lesson ${lesson.number} passed! `;
+        } else {
+            line.textContent = `Lesson ${lesson.number}: reach ${lesson.target_wpm} WPM ` +
+                `at ${lesson.target_accuracy}% accuracy to pass. `;
+        }
+        
+        const link = document.createElement('a');
+        link.href = '/lessons';
+        link.className = 'underline';
+        link.textContent = 'All lessons';
+        line.appendChild(link);
+        return line;
+    }
+    
+    // Function to describe a personal best for the completion message
+    function describePersonalBest(best) {
+        let name = 'overall';
+        if (best.category === 'text') {
+            name = 'this text';
+        } else if (best.category === 'mode') {
+            name = best.key + ' texts';
+        } else if (best.category === 'length') {
+            name = best.key + ' texts';
+        }
+        
+        if (best.test && best.test.mode !== 'text') {
+            name += ', ' + testName(best.test);
+        }
+        
+        if (best.previous_wpm) {
+            name += ` (+${(best.wpm - best.previous_wpm).toFixed(1)} WPM)`;
+        }
+        return name;
+    }
+    
+    // Function to describe a test like the server does
+    function testName(test) {
+        if (test.mode === 'time') {
+            return test.param + ' seconds';
+        } else if (test.mode === 'words') {
+            return test.param + ' words';
+        } else if (test.mode === 'sudden-death') {
+            return 'sudden death';
+        }
+        return 'full text';
+    }
+    
     // Function to show completion message
     function showCompletionMessage(message) {
         // Create a completion message element
@@ -391,7 +767,7 @@ function initTyping() {
         // Basic completion info
         let completionHTML = `
             <p class="font-bold mb-2">${message}</p>
-            <p>WPM: ${document.getElementById('wpm').textContent} | 
+            <p id="completion-summary">WPM: ${document.getElementById('wpm').textContent} | 
                Accuracy: ${document.getElementById('accuracy').textContent} | 
                Errors: ${document.getElementById('errors').textContent}</p>
         `;
@@ -403,7 +779,7 @@ function initTyping() {
             completionHTML += `
                 <div class="mt-4 p-3 bg-gray-700 rounded-lg">
                     <p class="font-bold mb-2">You made mistakes in these words:</p>
-                    <p class="mb-3">${errorWordsList.join(', ')}</p>
+                    <p class="mb-3">${escapeHTML(errorWordsList.join(', '))}</p>
                     <button 
                         id="practice-mistakes-btn"
                         class="px-4 py-2 bg-blue-500 hover:bg-blue-600 text-white font-bold rounded transition mr-2"
@@ -521,7 +897,7 @@ function initTyping() {
             console.error('Error generating practice text:', error);
             typingArea.innerHTML = `
                 <div class="bg-red-800 p-4 rounded-lg text-white text-center">
-                    <p>Failed to generate practice text: ${error.message}</p>
+                    <p>Failed to generate practice text: ${escapeHTML(error.message)}</p>
                     <button 
                         class="mt-2 px-4 py-2 bg-yellow-500 hover:bg-yellow-600 text-gray-900 font-bold rounded transition"
                         onclick="window.location.reload()"
@@ -532,4 +908,216 @@ function initTyping() {
             `;
         });
     }
-} 
\ No newline at end of file
+}
+
+// Open the event stream of a generated text. The first sentence starts the
+// exercise, the following ones and the stored text are passed on to it as
+// events on the text container.
+function streamText(textContainer, url) {
+    if (textStream) {
+        textStream.close();
+    }
+    const stream = new EventSource(url);
+    textStream = stream;
+    
+    stream.addEventListener('chunk', function(e) {
+        const content = JSON.parse(e.data).content;
+        if (!textContainer.dataset.content) {
+            textContainer.dataset.content = content;
+            initTyping();
+        } else {
+            textContainer.dispatchEvent(new CustomEvent('text-chunk', { detail: content }));
+        }
+    });
+    
+    stream.addEventListener('done', function(e) {
+        stream.close();
+        const data = JSON.parse(e.data);
+        if (!textContainer.dataset.content) {
+            // The text ended without a complete sentence, so it starts as a
+            // regular exercise
+            delete textContainer.dataset.stream;
+            textContainer.dataset.textId = data.text_id;
+            textContainer.dataset.content = data.content;
+            document.getElementById('typing-feedback').textContent =
+                "Ready to start typing... (Press ESC to end session early)";
+            initTyping();
+        } else {
+            textContainer.dispatchEvent(new CustomEvent('text-done', { detail: data }));
+        }
+    });
+    
+    stream.addEventListener('failed', function(e) {
+        stream.close();
+        failStream(textContainer, JSON.parse(e.data).error);
+    });
+    
+    // The browser would reconnect and generate another text
+    stream.onerror = function() {
+        if (stream.readyState !== EventSource.CLOSED) {
+            stream.close();
+            failStream(textContainer, 'Lost the connection to the server');
+        }
+    };
+}
+
+// Show why a streamed text couldn't be generated and stop its exercise
+function failStream(textContainer, message) {
+    console.error("Error generating text:", message);
+    const feedback = document.getElementById('typing-feedback');
+    feedback.textContent = 'Failed to generate text: ' + message;
+    feedback.className = 'text-center text-red-400';
+    if (textContainer.dataset.content) {
+        textContainer.dispatchEvent(new CustomEvent('text-failed', { detail: message }));
+    }
+}
+
+// Highlight the key of the next character on the on-screen keyboard,
+// together with Shift or AltGr and the finger to type it with. Characters
+// the layout can't type clear the highlight.
+function showNextKey(ch) {
+    const keyboard = document.getElementById('virtual-keyboard');
+    if (!keyboard) {
+        return;
+    }
+    keyboard.querySelectorAll('.kb-next').forEach(el => el.classList.remove('kb-next'));
+    
+    const key = ch ? keyboardKey(keyboard, ch) : null;
+    if (!key) {
+        return;
+    }
+    const marked = [
+        keyElement(keyboard, key),
+        keyboard.querySelector(`.kb-finger[data-finger="${key.finger}"]`)
+    ];
+    if (key.shift) {
+        // Shift is held by the pinky of the other hand
+        const hand = key.hand === 'left' ? 'right' : 'left';
+        marked.push(keyboard.querySelector(`[data-modifier="shift"][data-hand="${hand}"]`));
+    }
+    if (key.altgr) {
+        marked.push(keyboard.querySelector('[data-modifier="altgr"]'));
+    }
+    marked.forEach(el => el && el.classList.add('kb-next'));
+}
+
+// Flash the key that typed a wrong character on the on-screen keyboard
+function flashWrongKey(ch) {
+    const keyboard = document.getElementById('virtual-keyboard');
+    const key = keyboard ? keyboardKey(keyboard, ch) : null;
+    const el = key ? keyElement(keyboard, key) : null;
+    if (!el) {
+        return;
+    }
+    // Restart the animation when the same key is mistyped again
+    el.classList.remove('kb-wrong');
+    void el.offsetWidth;
+    el.classList.add('kb-wrong');
+}
+
+// Look up the key typing a character on the layout of the keyboard
+function keyboardKey(keyboard, ch) {
+    if (!keyboard.keyMap) {
+        keyboard.keyMap = JSON.parse(keyboard.dataset.keys);
+    }
+    return keyboard.keyMap[ch];
+}
+
+// Find the element of a key on the keyboard by its row and column
+function keyElement(keyboard, key) {
+    return keyboard.querySelector(`.kb-key[data-row="${key.row}"][data-column="${key.column}"]`);
+}
+
+// Let the user color the on-screen keyboard by the error rates of the keys
+function initKeyboard() {
+    const keyboard = document.getElementById('virtual-keyboard');
+    const toggle = document.getElementById('keyboard-heatmap');
+    if (!keyboard || !toggle) {
+        return;
+    }
+    const board = keyboard.querySelector('.kb-board');
+    
+    toggle.addEventListener('change', function() {
+        if (!toggle.checked) {
+            board.classList.remove('kb-heat');
+            return;
+        }
+        loadKeyHeat(keyboard)
+            .then(() => board.classList.add('kb-heat'))
+            .catch(error => {
+                console.error("Error loading key error rates:", error);
+                toggle.checked = false;
+            });
+    });
+}
+
+// Fetch the error rates of the keys once and mark each key with its heat
+// level. Keys without enough attempts stay gray.
+function loadKeyHeat(keyboard) {
+    if (!keyboard.heatPromise) {
+        keyboard.heatPromise = fetch('/api/v1/errors/keys')
+            .then(response => {
+                if (!response.ok) {
+                    throw new Error('Server responded with ' + response.status);
+                }
+                return response.json();
+            })
+            .then(data => {
+                keyboard.querySelectorAll('.kb-key[data-row]').forEach(el => el.classList.add('heat-none'));
+                for (const [ch, level] of Object.entries(data.heat)) {
+                    const key = keyboardKey(keyboard, ch);
+                    const el = key ? keyElement(keyboard, key) : null;
+                    if (el) {
+                        el.classList.replace('heat-none', 'heat-' + level);
+                    }
+                }
+            })
+            .catch(error => {
+                // Try again on the next toggle
+                keyboard.heatPromise = null;
+                throw error;
+            });
+    }
+    return keyboard.heatPromise;
+}
+
+// Split text into user-perceived characters (grapheme clusters)
+function splitGraphemes(text) {
+    text = text.normalize('NFC');
+    if (typeof Intl !== 'undefined' && Intl.Segmenter) {
+        const segmenter = new Intl.Segmenter(undefined, { granularity: 'grapheme' });
+        return Array.from(segmenter.segment(text), s => s.segment);
+    }
+    // Older browsers: at least keep surrogate pairs together
+    return Array.from(text);
+}
+
+// Check whether a key event produces a single character rather than
+// naming a key like "Shift" or "Enter"
+function isCharacterKey(key) {
+    return splitGraphemes(key).length === 1;
+}
+
+// Check whether the text consists only of combining marks
+function isCombiningMark(text) {
+    return /^\p{M}+$/u.test(text);
+}
+
+// Check whether a character can be part of a line's indentation
+function isIndent(ch) {
+    return ch === ' ' || ch === '\t';
+}
+
+// Escape a character for the display and mark line breaks
+function displayChar(ch) {
+    if (ch === '\n') {
+        return '↵\n';
+    }
+    return escapeHTML(ch);
+}
+
+// Escape text, e.g. words of a text another user wrote, for inserting into HTML
+function escapeHTML(text) {
+    return text.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;').replace(/'/g, '&#39;');
+}
@@ -8,7 +8,9 @@ package templates
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-func Base(content templ.Component) templ.Component {
+import "github.com/janislaus/figure10/internal/models"
+
+func Base(user models.User, content templ.Component) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -29,7 +31,35 @@ func Base(content templ.Component) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"en\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>Figure10 - Typing Trainer</title><script src=\"https://unpkg.com/htmx.org@1.9.6\"></script><script src=\"https://cdn.tailwindcss.com\"></script><link rel=\"stylesheet\" href=\"/static/css/style.css\"><script src=\"/static/js/typing.js\"></script></head><body class=\"bg-gray-900 text-gray-100 min-h-screen\"><div class=\"container mx-auto px-4 py-8\"><header class=\"mb-8\"><h1 class=\"text-4xl font-bold text-center text-yellow-400\">Figure10</h1><p class=\"text-center text-gray-400\">Your 10-finger typing trainer</p><nav class=\"mt-4 flex justify-center space-x-6\"><a href=\"/\" class=\"text-gray-300 hover:text-yellow-400\">Home</a> <a href=\"/history\" class=\"text-gray-300 hover:text-yellow-400\">History</a></nav></header><main>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"en\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>Figure10 - Typing Trainer</title><script src=\"https://unpkg.com/htmx.org@1.9.6\"></script><script src=\"https://cdn.tailwindcss.com\"></script><link rel=\"stylesheet\" href=\"/static/css/style.css\"><script src=\"/static/js/typing.js\"></script></head><body class=\"bg-gray-900 text-gray-100 min-h-screen\"><div class=\"container mx-auto px-4 py-8\"><header class=\"mb-8\"><h1 class=\"text-4xl font-bold text-center text-yellow-400\">Figure10</h1><p class=\"text-center text-gray-400\">Your 10-finger typing trainer</p><nav class=\"mt-4 flex justify-center space-x-6\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if user.ID != 0 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<a href=\"/\" class=\"text-gray-300 hover:text-yellow-400\">Home</a> <a href=\"/lessons\" class=\"text-gray-300 hover:text-yellow-400\">Lessons</a> <a href=\"/history\" class=\"text-gray-300 hover:text-yellow-400\">History</a> <a href=\"/library\" class=\"text-gray-300 hover:text-yellow-400\">Library</a> <a href=\"/leaderboard\" class=\"text-gray-300 hover:text-yellow-400\">Leaderboard</a> <a href=\"/import\" class=\"text-gray-300 hover:text-yellow-400\">Import</a> <a href=\"/settings\" class=\"text-gray-300 hover:text-yellow-400\">Settings</a><form action=\"/logout\" method=\"post\" class=\"inline\"><button type=\"submit\" class=\"text-gray-300 hover:text-yellow-400\">Log out (")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var2 string
+			templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(user.Username)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/base.templ`, Line: 32, Col: 96}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, ")</button></form>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<a href=\"/login\" class=\"text-gray-300 hover:text-yellow-400\">Log in</a> <a href=\"/register\" class=\"text-gray-300 hover:text-yellow-400\">Register</a>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</nav></header><main>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -37,7 +67,7 @@ func Base(content templ.Component) templ.Component {
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "</main><footer class=\"mt-12 text-center text-gray-500 text-sm\"><p>Figure10 - Improve your typing skills</p></footer></div></body></html>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "</main><footer class=\"mt-12 text-center text-gray-500 text-sm\"><p>Figure10 - Improve your typing skills</p></footer></div></body></html>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}